@@ -0,0 +1,126 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrAnnounceNotSigned     = errors.New("announce not signed")
+	ErrAnnounceMalformed     = errors.New("malformed announce")
+	ErrAnnounceBadSignature  = errors.New("invalid announce signature")
+	ErrAnnounceTimestamp     = errors.New("announce timestamp not in time range")
+	ErrAnnounceOnionKey      = errors.New("announce onion url not matching publicKey")
+	ErrAnnounceKeyNotAllowed = errors.New("announce publicKey not allowed for url")
+)
+
+type AnnounceInfo struct {
+	Version      string                `json:"version"`
+	Url          string                `json:"url"`
+	Capabilities *AnnounceCapabilities `json:"capabilities,omitempty"`
+	// Load is the number of rpc requests during the last minute
+	Load      int    `json:"load,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	PublicKey string `json:"publickey,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// AnnounceCapabilities describe node features for clients
+type AnnounceCapabilities struct {
+	Methods []string       `json:"methods"`
+	P2P     bool           `json:"p2p"`
+	Limits  AnnounceLimits `json:"limits"`
+}
+
+// AnnounceLimits of node requests, durations in seconds
+type AnnounceLimits struct {
+	// SignatureWindow is the maximum delta between signed requests timestamp and server time
+	SignatureWindow int64 `json:"signaturewindow"`
+	// MaxTTL is the longest entries time to live
+	MaxTTL int64 `json:"maxttl"`
+}
+
+// Message return announce signed message, announce json without signature
+func (p AnnounceInfo) Message() ([]byte, error) {
+	p.Signature = ""
+	return json.Marshal(&p)
+}
+
+// Verify check announce signature and timestamp, onion urls must match the signing key.
+// Other urls can't be bound to the key, they must be signed by one of keys.
+func (p *AnnounceInfo) Verify(ttl time.Duration, keys ...string) error {
+	if len(p.Signature) == 0 {
+		return ErrAnnounceNotSigned
+	}
+	publicKey, err := hex.DecodeString(p.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return ErrAnnounceMalformed
+	}
+	signature, err := hex.DecodeString(p.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return ErrAnnounceMalformed
+	}
+	message, err := p.Message()
+	if err != nil {
+		return ErrAnnounceMalformed
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		return ErrAnnounceBadSignature
+	}
+
+	// announces are valid during entry time to live
+	now := time.Now()
+	t := time.Unix(0, p.Timestamp)
+	if t.Before(now.Add(-ttl)) || t.After(now.Add(ttl)) {
+		return ErrAnnounceTimestamp
+	}
+
+	u, err := url.Parse(p.Url)
+	if err != nil {
+		return ErrAnnounceMalformed
+	}
+	if host := u.Hostname(); strings.HasSuffix(host, ".onion") {
+		if strings.TrimSuffix(host, ".onion") != OnionServiceID(publicKey) {
+			return ErrAnnounceOnionKey
+		}
+		return nil
+	}
+	for _, key := range keys {
+		if strings.EqualFold(key, p.PublicKey) {
+			return nil
+		}
+	}
+	return ErrAnnounceKeyNotAllowed
+}
+
+// Unverified return true if err is returned for announces not signed, or not bound to their url
+func Unverified(err error) bool {
+	return errors.Is(err, ErrAnnounceNotSigned) || errors.Is(err, ErrAnnounceKeyNotAllowed)
+}
+
+// onion v3 address version
+const onionVersion = 0x03
+
+var onionEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OnionServiceID return the onion v3 service id (address without .onion) of ed25519 publicKey
+func OnionServiceID(publicKey ed25519.PublicKey) string {
+	checksum := sha3.New256()
+	checksum.Write([]byte(".onion checksum"))
+	checksum.Write(publicKey)
+	checksum.Write([]byte{onionVersion})
+
+	data := make([]byte, 0, len(publicKey)+3)
+	data = append(data, publicKey...)
+	data = append(data, checksum.Sum(nil)[:2]...)
+	data = append(data, onionVersion)
+	return strings.ToLower(onionEncoding.EncodeToString(data))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signAnnounce sign info as nodes do with their onion key
+func signAnnounce(t *testing.T, info *AnnounceInfo, privateKey ed25519.PrivateKey) {
+	t.Helper()
+	info.Timestamp = time.Now().UnixNano()
+	info.PublicKey = hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	message, err := info.Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.Signature = hex.EncodeToString(ed25519.Sign(privateKey, message))
+}
+
+func Test_OnionServiceID(t *testing.T) {
+	publicKey, _ := hex.DecodeString("93a39ad12e648a14059fc3b6aee8bb9e82bf45cb4212d67e105f3611389a55ec")
+	if id := OnionServiceID(publicKey); id != "sorzvujomsfbibm7yo3k52f3t2bl6roliijnm7qql43bcoe2kxwhbcyd" {
+		t.Errorf("OnionServiceID() = %s", id)
+	}
+}
+
+func Test_AnnounceInfoVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onion := "http://" + OnionServiceID(publicKey) + ".onion"
+
+	info := AnnounceInfo{
+		Version:      "1.0",
+		Url:          onion,
+		Capabilities: &AnnounceCapabilities{Methods: []string{MethodList}, P2P: true},
+		Load:         12,
+	}
+	signAnnounce(t, &info, privateKey)
+	if err := info.Verify(time.Minute); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+
+	tampered := info
+	tampered.Load = 0
+	if err := tampered.Verify(time.Minute); !errors.Is(err, ErrAnnounceBadSignature) {
+		t.Errorf("Verify() tampered = %v", err)
+	}
+
+	// onion url must match signing key
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	info = AnnounceInfo{Version: "1.0", Url: onion}
+	signAnnounce(t, &info, other)
+	if err := info.Verify(time.Minute); !errors.Is(err, ErrAnnounceOnionKey) {
+		t.Errorf("Verify() other key = %v", err)
+	}
+
+	// clearnet urls can't be bound to the key, keys must be allowed
+	info = AnnounceInfo{Version: "1.0", Url: "http://127.0.0.1:4242"}
+	signAnnounce(t, &info, other)
+	if err := info.Verify(time.Minute); !errors.Is(err, ErrAnnounceKeyNotAllowed) || !Unverified(err) {
+		t.Errorf("Verify() clearnet = %v", err)
+	}
+	allowed := hex.EncodeToString(other.Public().(ed25519.PublicKey))
+	if err := info.Verify(time.Minute, strings.ToUpper(allowed)); err != nil {
+		t.Errorf("Verify() clearnet allowed = %v", err)
+	}
+	if err := info.Verify(-time.Minute, allowed); !errors.Is(err, ErrAnnounceTimestamp) {
+		t.Errorf("Verify() expired = %v", err)
+	}
+
+	unsigned := AnnounceInfo{Version: "1.0", Url: onion}
+	if err := unsigned.Verify(time.Minute); !errors.Is(err, ErrAnnounceNotSigned) {
+		t.Errorf("Verify() unsigned = %v", err)
+	}
+}
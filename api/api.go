@@ -0,0 +1,16 @@
+// Package api contains soroban json-rpc request and response types, with response and announce verification.
+// It has no dependency on server packages and is shared by services and clients.
+package api
+
+// json-rpc methods of directory and node services
+const (
+	MethodList     = "directory.List"
+	MethodAdd      = "directory.Add"
+	MethodRemove   = "directory.Remove"
+	MethodNodeInfo = "node.Info"
+)
+
+// Response for json-rpc directory updates
+type Response struct {
+	Status string
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// AlgorithmEd25519 is the node signing key algorithm
+	AlgorithmEd25519 = "ed25519"
+	// DefaultResponseMaxAge is the maximum delta between signed responses timestamp and client time
+	DefaultResponseMaxAge = 5 * time.Minute
+)
+
+var (
+	ErrResponseNotSigned    = errors.New("response not signed")
+	ErrResponseKeyMismatch  = errors.New("response signed by an other key")
+	ErrResponseBadSignature = errors.New("invalid response signature")
+	ErrResponseMalformed    = errors.New("malformed response signature")
+	ErrResponseNotPinned    = errors.New("response signing key not pinned")
+	ErrResponseTimestamp    = errors.New("response timestamp not in time range")
+)
+
+// DirectoryEntries for json-rpc request
+type DirectoryEntries struct {
+	Name      string
+	Limit     int
+	PublicKey string
+	Algorithm string
+	Signature string
+	Timestamp int64
+	Version   int
+	Nonce     string
+}
+
+// DirectoryEntriesResponse for json-rpc response, signed with node key if enabled
+type DirectoryEntriesResponse struct {
+	Name      string
+	Entries   []string
+	Timestamp int64  `json:",omitempty"`
+	PublicKey string `json:",omitempty"`
+	Signature string `json:",omitempty"`
+}
+
+// DirectoryEntry for json-rpc request
+type DirectoryEntry struct {
+	Name      string
+	Entry     string
+	Mode      string
+	PublicKey string
+	Algorithm string
+	Signature string
+	Timestamp int64
+	Version   int
+	Nonce     string
+}
+
+// NodeInfoArgs for json-rpc request
+type NodeInfoArgs struct{}
+
+// NodeInfoResponse for json-rpc response
+type NodeInfoResponse struct {
+	// PublicKey is the hex encoded node signing key, empty if responses are not signed
+	PublicKey string
+	Algorithm string
+	// Onion is the onion service id of the signing key
+	Onion string
+}
+
+// ListResponseMessage return the message signed for a list response:
+// directory.List.name.timestamp.digest, with digest the hex sha256 of entries sha256 concatenation
+func ListResponseMessage(name string, timestamp int64, entries []string) string {
+	digest := sha256.New()
+	for _, entry := range entries {
+		hash := sha256.Sum256([]byte(entry))
+		digest.Write(hash[:])
+	}
+	return fmt.Sprintf("%s.%s.%d.%s", MethodList, name, timestamp, hex.EncodeToString(digest.Sum(nil)))
+}
+
+// Verify check response signature with pinned publicKey and timestamp within maxAge of current time,
+// DefaultResponseMaxAge if maxAge is 0. A stale response can be replayed until maxAge.
+func (p *DirectoryEntriesResponse) Verify(publicKey string, maxAge time.Duration) error {
+	if len(publicKey) == 0 {
+		return ErrResponseNotPinned
+	}
+	if len(p.Signature) == 0 {
+		return ErrResponseNotSigned
+	}
+	if !strings.EqualFold(publicKey, p.PublicKey) {
+		return ErrResponseKeyMismatch
+	}
+
+	key, err := hex.DecodeString(p.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return ErrResponseMalformed
+	}
+	signature, err := hex.DecodeString(p.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return ErrResponseMalformed
+	}
+	if !ed25519.Verify(key, []byte(ListResponseMessage(p.Name, p.Timestamp, p.Entries)), signature) {
+		return ErrResponseBadSignature
+	}
+
+	if maxAge <= 0 {
+		maxAge = DefaultResponseMaxAge
+	}
+	now := time.Now()
+	t := time.Unix(0, p.Timestamp)
+	if t.Before(now.Add(-maxAge)) || t.After(now.Add(maxAge)) {
+		return ErrResponseTimestamp
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signResponse sign response as nodes do with their signing key
+func signResponse(response *DirectoryEntriesResponse, signer ed25519.PrivateKey) {
+	response.Timestamp = time.Now().UnixNano()
+	response.PublicKey = hex.EncodeToString(signer.Public().(ed25519.PublicKey))
+	response.Signature = hex.EncodeToString(ed25519.Sign(signer, []byte(ListResponseMessage(response.Name, response.Timestamp, response.Entries))))
+}
+
+func Test_DirectoryEntriesResponseVerify(t *testing.T) {
+	publicKey, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned := hex.EncodeToString(publicKey)
+
+	response := DirectoryEntriesResponse{
+		Name:    "name",
+		Entries: []string{"a.b", "c"},
+	}
+	if err := response.Verify(pinned, 0); !errors.Is(err, ErrResponseNotSigned) {
+		t.Errorf("Verify() unsigned = %v", err)
+	}
+
+	signResponse(&response, signer)
+	if err := response.Verify(pinned, 0); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	if err := response.Verify(strings.ToUpper(pinned), time.Minute); err != nil {
+		t.Errorf("Verify() upper case pin = %v", err)
+	}
+	if err := response.Verify("", 0); !errors.Is(err, ErrResponseNotPinned) {
+		t.Errorf("Verify() without pin = %v", err)
+	}
+	if err := response.Verify(hex.EncodeToString(make([]byte, ed25519.PublicKeySize)), 0); !errors.Is(err, ErrResponseKeyMismatch) {
+		t.Errorf("Verify() other key = %v", err)
+	}
+
+	// entries boundaries are signed
+	tampered := response
+	tampered.Entries = []string{"a", "b.c"}
+	if err := tampered.Verify(pinned, 0); !errors.Is(err, ErrResponseBadSignature) {
+		t.Errorf("Verify() tampered = %v", err)
+	}
+	tampered = response
+	tampered.Timestamp++
+	if err := tampered.Verify(pinned, 0); !errors.Is(err, ErrResponseBadSignature) {
+		t.Errorf("Verify() timestamp = %v", err)
+	}
+
+	// stale responses are rejected
+	stale := DirectoryEntriesResponse{Name: "name", Entries: []string{"a"}}
+	signResponse(&stale, signer)
+	stale.Timestamp = time.Now().Add(-time.Hour).UnixNano()
+	stale.Signature = hex.EncodeToString(ed25519.Sign(signer, []byte(ListResponseMessage(stale.Name, stale.Timestamp, stale.Entries))))
+	if err := stale.Verify(pinned, 0); !errors.Is(err, ErrResponseTimestamp) {
+		t.Errorf("Verify() stale = %v", err)
+	}
+	if err := stale.Verify(pinned, 2*time.Hour); err != nil {
+		t.Errorf("Verify() max age = %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Announces return node announces of announce key, announces are verified unless unsigned announces are accepted.
+// Onion nodes are skipped without socks proxy, announces are sorted by load.
+func (p *Client) Announces(ctx context.Context) ([]api.AnnounceInfo, error) {
+	if len(p.options.AnnounceKey) == 0 {
+		return nil, errors.New("announce key not set")
+	}
+	// announces are public, list request is not signed
+	entries, err := p.WithSigner(nil).List(ctx, p.options.AnnounceKey, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []api.AnnounceInfo
+	for _, entry := range entries {
+		var info api.AnnounceInfo
+		err := json.Unmarshal([]byte(entry), &info)
+		if err != nil {
+			log.WithError(err).Debug("Invalid announce")
+			continue
+		}
+		err = info.Verify(p.options.AnnounceTTL, p.options.AnnounceKeys...)
+		if api.Unverified(err) && p.options.AnnounceUnsigned {
+			err = nil
+		}
+		if err != nil {
+			log.WithError(err).WithField("URL", info.Url).Debug("Announce not verified")
+			continue
+		}
+		if onion(info.Url) && len(p.options.Socks) == 0 {
+			continue
+		}
+		result = append(result, info)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Load < result[j].Load
+	})
+	return result, nil
+}
+
+// Discover add announced nodes to client nodes, return the number of new nodes
+func (p *Client) Discover(ctx context.Context) (int, error) {
+	announces, err := p.Announces(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, info := range announces {
+		rpcURL, err := RPCURL(info.Url)
+		if err != nil {
+			continue
+		}
+		if p.nodes.add(rpcURL) {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,407 @@
+// Package client is a Go client for soroban json-rpc services
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+
+	gjson "github.com/gorilla/rpc/json"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/proxy"
+)
+
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultRetries    = 2
+	DefaultRetryDelay = time.Second
+)
+
+var (
+	ErrNoNodes       = errors.New("no soroban node")
+	ErrRequestFailed = errors.New("soroban request failed")
+)
+
+// Options configure client nodes and requests
+type Options struct {
+	// URLs of soroban nodes, http://host:port or json-rpc url
+	URLs []string
+	// AnnounceKey is the directory key of node announces, see Discover
+	AnnounceKey string
+	// AnnounceUnsigned accept unsigned announces, and announces of non-onion urls not signed by AnnounceKeys
+	AnnounceUnsigned bool
+	// AnnounceKeys are the hex ed25519 keys allowed to sign announces of non-onion urls
+	AnnounceKeys []string
+	// AnnounceTTL is the maximum age of announces
+	AnnounceTTL time.Duration
+
+	// Socks is the socks5 proxy address (tor), all requests are sent through the proxy
+	Socks string
+	// Timeout of each node request
+	Timeout time.Duration
+	// Retries is the number of rounds over nodes after the first one, DefaultRetries if 0, none if negative
+	Retries int
+	// RetryDelay is the delay between rounds
+	RetryDelay time.Duration
+
+	// Signer sign directory requests, requests are anonymous if nil
+	Signer Signer
+	// Pin is the node signing key, list responses are verified if set
+	Pin string
+	// ResponseMaxAge is the maximum age of verified list responses, api.DefaultResponseMaxAge if 0
+	ResponseMaxAge time.Duration
+}
+
+// Client send json-rpc requests to soroban nodes, failed nodes are skipped until next round
+type Client struct {
+	options Options
+	client  *http.Client
+	nodes   *nodes
+}
+
+// nodes shared by clients, the last successful node is used first
+type nodes struct {
+	mtx     sync.Mutex
+	urls    []string
+	current int
+}
+
+// New return client for options, Discover add announced nodes
+func New(options Options) (*Client, error) {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.Retries == 0 {
+		options.Retries = DefaultRetries
+	} else if options.Retries < 0 {
+		options.Retries = 0
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = DefaultRetryDelay
+	}
+	if options.AnnounceTTL <= 0 {
+		options.AnnounceTTL = 5 * time.Minute
+	}
+
+	client, err := newHTTPClient(options.Socks)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := new(nodes)
+	for _, nodeURL := range options.URLs {
+		rpcURL, err := RPCURL(nodeURL)
+		if err != nil {
+			return nil, err
+		}
+		nodes.add(rpcURL)
+	}
+
+	return &Client{
+		options: options,
+		client:  client,
+		nodes:   nodes,
+	}, nil
+}
+
+// newHTTPClient return http client, requests are dialed through socks proxy if set
+func newHTTPClient(socks string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(socks) > 0 {
+		dialer, err := proxy.SOCKS5("tcp", socks, nil, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, errors.New("socks dialer without context")
+		}
+		transport.Proxy = nil
+		transport.DialContext = contextDialer.DialContext
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// RPCURL return json-rpc url of node url, /rpc path is added to node urls without path
+func RPCURL(nodeURL string) (string, error) {
+	u, err := url.Parse(nodeURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid node url: %s", nodeURL)
+	}
+	if len(u.Path) == 0 || u.Path == "/" {
+		u.Path = "/rpc"
+	}
+	return u.String(), nil
+}
+
+// WithSigner return a client sharing nodes with requests signed by signer
+func (p *Client) WithSigner(signer Signer) *Client {
+	options := p.options
+	options.Signer = signer
+	return &Client{
+		options: options,
+		client:  p.client,
+		nodes:   p.nodes,
+	}
+}
+
+// Nodes return json-rpc urls of nodes
+func (p *Client) Nodes() []string {
+	p.nodes.mtx.Lock()
+	defer p.nodes.mtx.Unlock()
+
+	return append([]string(nil), p.nodes.urls...)
+}
+
+func (p *nodes) add(rpcURL string) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for _, u := range p.urls {
+		if u == rpcURL {
+			return false
+		}
+	}
+	p.urls = append(p.urls, rpcURL)
+	return true
+}
+
+// round return nodes starting with current node
+func (p *nodes) round() []string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	result := make([]string, 0, len(p.urls))
+	result = append(result, p.urls[p.current:]...)
+	result = append(result, p.urls[:p.current]...)
+	return result
+}
+
+func (p *nodes) setCurrent(rpcURL string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for i, u := range p.urls {
+		if u == rpcURL {
+			p.current = i
+			return
+		}
+	}
+}
+
+// Call send json-rpc request to nodes until one succeed
+func (p *Client) Call(ctx context.Context, method string, args, result interface{}) error {
+	return p.call(ctx, method, func() (interface{}, error) { return args, nil }, result, nil)
+}
+
+// call send request built for each attempt, signed requests use a new timestamp and nonce.
+// Node result is rejected by check if not nil.
+func (p *Client) call(ctx context.Context, method string, build func() (interface{}, error), result interface{}, check func() error) error {
+	if len(p.Nodes()) == 0 {
+		return ErrNoNodes
+	}
+
+	var err error
+	for attempt := 0; attempt <= p.options.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.options.RetryDelay):
+			}
+		}
+
+		urls := p.nodes.round()
+		for _, rpcURL := range urls {
+			var args interface{}
+			args, err = build()
+			if err != nil {
+				return err
+			}
+			err = p.send(ctx, rpcURL, method, args, result)
+			if err == nil && check != nil {
+				err = check()
+			}
+			if err == nil {
+				p.nodes.setCurrent(rpcURL)
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.WithError(err).WithField("URL", rpcURL).WithField("Method", method).Debug("Soroban request failed")
+		}
+	}
+	return fmt.Errorf("%w: %s: %s", ErrRequestFailed, method, err)
+}
+
+// send json-rpc request to node with client timeout
+func (p *Client) send(ctx context.Context, rpcURL, method string, args, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, p.options.Timeout)
+	defer cancel()
+
+	body, err := gjson.EncodeClientRequest(method, args)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	return gjson.DecodeClientResponse(resp.Body, result)
+}
+
+// signature of directory request
+type signature struct {
+	PublicKey string
+	Algorithm string
+	Signature string
+	Timestamp int64
+	Nonce     string
+}
+
+// sign return version 2 signature of method request, empty without signer
+func (p *Client) sign(method, name string, entry ...string) (signature, error) {
+	signer := p.options.Signer
+	if signer == nil {
+		return signature{}, nil
+	}
+
+	data := make([]byte, 16)
+	_, err := rand.Read(data)
+	if err != nil {
+		return signature{}, err
+	}
+	result := signature{
+		PublicKey: signer.PublicKey(),
+		Algorithm: signer.Algorithm(),
+		Timestamp: time.Now().UnixNano(),
+		Nonce:     hex.EncodeToString(data),
+	}
+	result.Signature, err = signer.Sign(confidential.RequestMessage(confidential.SignatureV2, method, name, result.Timestamp, result.Nonce, entry...))
+	if err != nil {
+		return signature{}, err
+	}
+	return result, nil
+}
+
+// List return entries of name, at most limit random entries if limit > 0
+func (p *Client) List(ctx context.Context, name string, limit int) ([]string, error) {
+	var result api.DirectoryEntriesResponse
+	err := p.call(ctx, api.MethodList,
+		func() (interface{}, error) {
+			signed, err := p.sign(api.MethodList, name)
+			if err != nil {
+				return nil, err
+			}
+			return &api.DirectoryEntries{
+				Name:      name,
+				Limit:     limit,
+				PublicKey: signed.PublicKey,
+				Algorithm: signed.Algorithm,
+				Signature: signed.Signature,
+				Timestamp: signed.Timestamp,
+				Version:   versionOf(signed),
+				Nonce:     signed.Nonce,
+			}, nil
+		},
+		&result,
+		func() error {
+			if len(p.options.Pin) == 0 {
+				return nil
+			}
+			return result.Verify(p.options.Pin, p.options.ResponseMaxAge)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// Add entry to name with ttl mode
+func (p *Client) Add(ctx context.Context, name, entry, mode string) error {
+	return p.update(ctx, api.MethodAdd, name, entry, mode)
+}
+
+// Remove entry from name
+func (p *Client) Remove(ctx context.Context, name, entry string) error {
+	return p.update(ctx, api.MethodRemove, name, entry, "")
+}
+
+func (p *Client) update(ctx context.Context, method, name, entry, mode string) error {
+	var result api.Response
+	err := p.call(ctx, method,
+		func() (interface{}, error) {
+			signed, err := p.sign(method, name, entry)
+			if err != nil {
+				return nil, err
+			}
+			return &api.DirectoryEntry{
+				Name:      name,
+				Entry:     entry,
+				Mode:      mode,
+				PublicKey: signed.PublicKey,
+				Algorithm: signed.Algorithm,
+				Signature: signed.Signature,
+				Timestamp: signed.Timestamp,
+				Version:   versionOf(signed),
+				Nonce:     signed.Nonce,
+			}, nil
+		},
+		&result,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	// rejected requests are not sent to other nodes
+	if result.Status != "success" {
+		return fmt.Errorf("%w: %s: %s", ErrRequestFailed, method, result.Status)
+	}
+	return nil
+}
+
+func versionOf(signed signature) int {
+	if len(signed.Signature) == 0 {
+		return 0
+	}
+	return confidential.SignatureV2
+}
+
+// NodeInfo return node signing key of the first available node
+func (p *Client) NodeInfo(ctx context.Context) (api.NodeInfoResponse, error) {
+	var result api.NodeInfoResponse
+	err := p.Call(ctx, api.MethodNodeInfo, &api.NodeInfoArgs{}, &result)
+	return result, err
+}
+
+// onion return true if url host is an onion service
+func onion(nodeURL string) bool {
+	u, err := url.Parse(nodeURL)
+	return err == nil && strings.HasSuffix(u.Hostname(), ".onion")
+}
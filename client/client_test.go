@@ -0,0 +1,412 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+	"code.samourai.io/wallet/samourai-soroban/server"
+	"code.samourai.io/wallet/samourai-soroban/services"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/cretz/bine/torutil"
+	torEd25519 "github.com/cretz/bine/torutil/ed25519"
+	"golang.org/x/crypto/nacl/sign"
+)
+
+// startServer start an in-process soroban node, return node context and url
+func startServer(t *testing.T, signingSeed string) (context.Context, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	options := soroban.DefaultOptions
+	options.Soroban.Hostname = "127.0.0.1"
+	options.Soroban.Port = port
+	options.Soroban.DirectoryType = "memory"
+	options.Soroban.SigningSeed = signingSeed
+
+	ctx, sorobanServer := server.New(context.Background(), options)
+	if sorobanServer == nil {
+		t.Fatal("server not created")
+	}
+	err = services.RegisterAll(ctx, sorobanServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sorobanServer.Start(ctx, options.Soroban.Hostname, options.Soroban.Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorobanServer.WaitForStart(ctx)
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return ctx, "http://" + addr
+}
+
+func newClient(t *testing.T, options Options) *Client {
+	t.Helper()
+
+	client, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func Test_ClientDirectory(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	rand.Read(seed)
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+
+	_, nodeURL := startServer(t, hex.EncodeToString(seed))
+	client := newClient(t, Options{
+		URLs: []string{nodeURL},
+		Pin:  hex.EncodeToString(publicKey),
+	})
+	ctx := context.Background()
+
+	err := client.Add(ctx, "client.directory", "entry", "short")
+	if err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	entries, err := client.List(ctx, "client.directory", 0)
+	if err != nil || len(entries) != 1 || entries[0] != "entry" {
+		t.Fatalf("List() = %v, %v", entries, err)
+	}
+	err = client.Remove(ctx, "client.directory", "entry")
+	if err != nil {
+		t.Fatalf("Remove() = %v", err)
+	}
+	entries, err = client.List(ctx, "client.directory", 0)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("List() = %v, %v", entries, err)
+	}
+
+	info, err := client.NodeInfo(ctx)
+	if err != nil || info.PublicKey != hex.EncodeToString(publicKey) {
+		t.Errorf("NodeInfo() = %v, %v", info, err)
+	}
+
+	// responses signed by an other key are rejected
+	pinned := newClient(t, Options{
+		URLs:    []string{nodeURL},
+		Pin:     hex.EncodeToString(make([]byte, ed25519.PublicKeySize)),
+		Retries: -1,
+	})
+	_, err = pinned.List(ctx, "client.directory", 0)
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Errorf("List() pinned = %v", err)
+	}
+}
+
+func Test_ClientSigners(t *testing.T) {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signers := []Signer{
+		NewNaclSigner(publicKey, privateKey),
+		NewEcdsaSigner(key),
+		NewSchnorrSigner(key),
+	}
+	for _, algorithm := range []string{confidential.AlgorithmMainnet, confidential.AlgorithmTestnet3, confidential.AlgorithmSignet, confidential.AlgorithmRegtest} {
+		signer, err := NewLegacySigner(algorithm, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+	for _, params := range []*chaincfg.Params{&chaincfg.MainNetParams, &chaincfg.TestNet3Params} {
+		signer, err := NewBip322Signer(key, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+		signer, err = NewBip322TaprootSigner(key, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+
+	previous := confidential.DefaultSorobanConfig
+	defer func() { confidential.DefaultSorobanConfig = previous }()
+	var config confidential.SorobanConfig
+	for i, signer := range signers {
+		config.Confidential = append(config.Confidential, confidential.ConfidentialEntry{
+			Prefix:       fmt.Sprintf("signer.%d.*", i),
+			Algorithm:    signer.Algorithm(),
+			PublicKey:    signer.PublicKey(),
+			Confidential: true,
+			ReadOnly:     true,
+		})
+	}
+	confidential.DefaultSorobanConfig = config
+
+	_, nodeURL := startServer(t, "")
+	anonymous := newClient(t, Options{URLs: []string{nodeURL}})
+	ctx := context.Background()
+
+	for i, signer := range signers {
+		name := fmt.Sprintf("signer.%d.key", i)
+		client := anonymous.WithSigner(signer)
+
+		err := anonymous.Add(ctx, name, "anonymous", "short")
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Errorf("%s %s: anonymous Add() = %v", signer.Algorithm(), signer.PublicKey(), err)
+		}
+		err = client.Add(ctx, name, "entry", "short")
+		if err != nil {
+			t.Errorf("%s %s: Add() = %v", signer.Algorithm(), signer.PublicKey(), err)
+			continue
+		}
+		entries, err := client.List(ctx, name, 0)
+		if err != nil || len(entries) != 1 {
+			t.Errorf("%s %s: List() = %v, %v", signer.Algorithm(), signer.PublicKey(), entries, err)
+		}
+		entries, _ = anonymous.List(ctx, name, 0)
+		if len(entries) != 0 {
+			t.Errorf("%s %s: anonymous List() = %v", signer.Algorithm(), signer.PublicKey(), entries)
+		}
+		err = client.Remove(ctx, name, "entry")
+		if err != nil {
+			t.Errorf("%s %s: Remove() = %v", signer.Algorithm(), signer.PublicKey(), err)
+		}
+	}
+}
+
+func Test_ClientFailover(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	_, nodeURL := startServer(t, "")
+	client := newClient(t, Options{
+		URLs:    []string{deadURL, nodeURL},
+		Timeout: time.Second,
+		Retries: -1,
+	})
+	ctx := context.Background()
+
+	err = client.Add(ctx, "client.failover", "entry", "short")
+	if err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	// last successful node is used first
+	if urls := client.nodes.round(); urls[0] != nodeURL+"/rpc" {
+		t.Errorf("round() = %v", urls)
+	}
+
+	dead := newClient(t, Options{
+		URLs:       []string{deadURL},
+		Timeout:    time.Second,
+		Retries:    1,
+		RetryDelay: 10 * time.Millisecond,
+	})
+	_, err = dead.List(ctx, "client.failover", 0)
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Errorf("List() dead = %v", err)
+	}
+	_, err = newClient(t, Options{}).List(ctx, "client.failover", 0)
+	if !errors.Is(err, ErrNoNodes) {
+		t.Errorf("List() no nodes = %v", err)
+	}
+}
+
+func Test_ClientDiscover(t *testing.T) {
+	announceCtx, nodeURL := startServer(t, "")
+	_, announcedURL := startServer(t, "")
+
+	publicKey, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onionURL := "http://" + torutil.OnionServiceIDFromV3PublicKey(torEd25519.PublicKey(publicKey)) + ".onion"
+	ctx, cancel := context.WithCancel(announceCtx)
+	defer cancel()
+	go services.StartAnnounce(ctx, services.AnnounceOptions{
+		Key:      "client.announce",
+		Version:  "test",
+		URLs:     []string{announcedURL, onionURL},
+		Interval: 20 * time.Millisecond,
+		Mode:     "short",
+		Signer:   signer,
+	})
+
+	// clearnet announces must be signed by an allowed key
+	notAllowed := newClient(t, Options{
+		URLs:        []string{nodeURL},
+		AnnounceKey: "client.announce",
+	})
+	client := newClient(t, Options{
+		URLs:         []string{nodeURL},
+		AnnounceKey:  "client.announce",
+		AnnounceKeys: []string{hex.EncodeToString(publicKey)},
+	})
+	for i := 0; i < 100; i++ {
+		announces, err := client.Announces(context.Background())
+		if err == nil && len(announces) > 0 {
+			if announces[0].Url != announcedURL {
+				t.Errorf("Announces() = %v", announces)
+			}
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// announces are not signed again on each tick
+	first, _ := client.Announces(context.Background())
+	time.Sleep(60 * time.Millisecond)
+	second, err := client.Announces(context.Background())
+	if err != nil || len(first) != 1 || len(second) != 1 || first[0].Timestamp != second[0].Timestamp {
+		t.Errorf("Announces() = %v, %v, %v", first, second, err)
+	}
+
+	announces, err := notAllowed.Announces(context.Background())
+	if err != nil || len(announces) != 0 {
+		t.Errorf("Announces() not allowed = %v, %v", announces, err)
+	}
+
+	// onion node is skipped without socks proxy
+	count, err := client.Discover(context.Background())
+	if err != nil || count != 1 {
+		t.Fatalf("Discover() = %d, %v", count, err)
+	}
+	nodes := client.Nodes()
+	if len(nodes) != 2 || nodes[1] != announcedURL+"/rpc" {
+		t.Errorf("Nodes() = %v", nodes)
+	}
+
+	// unsigned announces are rejected
+	cancel()
+	unsigned := newClient(t, Options{
+		URLs:        []string{nodeURL},
+		AnnounceKey: "client.unsigned",
+	})
+	err = unsigned.Add(context.Background(), "client.unsigned", `{"version":"test","url":"http://127.0.0.1:1"}`, "short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	announces, err = unsigned.Announces(context.Background())
+	if err != nil || len(announces) != 0 {
+		t.Errorf("Announces() unsigned = %v, %v", announces, err)
+	}
+}
+
+// socksServer is a minimal socks5 proxy without authentication, return proxy address
+func socksServer(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				// greeting: version, methods count, methods
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+					return
+				}
+				conn.Write([]byte{5, 0})
+
+				// connect request with ipv4 address
+				request := make([]byte, 10)
+				if _, err := io.ReadFull(conn, request); err != nil || request[3] != 1 {
+					return
+				}
+				target, err := net.Dial("tcp", net.JoinHostPort(net.IP(request[4:8]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(request[8:])))))
+				if err != nil {
+					conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func Test_ClientSocks(t *testing.T) {
+	_, nodeURL := startServer(t, "")
+	ctx := context.Background()
+
+	client := newClient(t, Options{
+		URLs:  []string{nodeURL},
+		Socks: socksServer(t),
+	})
+	err := client.Add(ctx, "client.socks", "entry", "short")
+	if err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	entries, err := client.List(ctx, "client.socks", 0)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("List() = %v, %v", entries, err)
+	}
+
+	// unreachable proxy
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socks := listener.Addr().String()
+	listener.Close()
+	client = newClient(t, Options{
+		URLs:    []string{nodeURL},
+		Socks:   socks,
+		Retries: -1,
+	})
+	_, err = client.List(ctx, "client.socks", 0)
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Errorf("List() = %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"golang.org/x/crypto/nacl/sign"
+)
+
+// Signer sign directory requests for confidential keys
+type Signer interface {
+	// Algorithm is the confidential key algorithm
+	Algorithm() string
+	// PublicKey is the key, or address, configured for confidential prefixes
+	PublicKey() string
+	// Sign return encoded signature of message
+	Sign(message string) (string, error)
+}
+
+// legacy signed message networks
+var legacyNetworks = map[string]*chaincfg.Params{
+	confidential.AlgorithmMainnet:  &chaincfg.MainNetParams,
+	confidential.AlgorithmTestnet3: &chaincfg.TestNet3Params,
+	confidential.AlgorithmSignet:   &chaincfg.SigNetParams,
+	confidential.AlgorithmRegtest:  &chaincfg.RegressionNetParams,
+}
+
+type naclSigner struct {
+	publicKey  *[32]byte
+	privateKey *[64]byte
+}
+
+// NewNaclSigner return signer for nacl keys
+func NewNaclSigner(publicKey *[32]byte, privateKey *[64]byte) Signer {
+	return &naclSigner{
+		publicKey:  publicKey,
+		privateKey: privateKey,
+	}
+}
+
+func (p *naclSigner) Algorithm() string {
+	return confidential.AlgorithmNacl
+}
+
+func (p *naclSigner) PublicKey() string {
+	return hex.EncodeToString(p.publicKey[:])
+}
+
+// Sign return detached signature
+func (p *naclSigner) Sign(message string) (string, error) {
+	signed := sign.Sign(nil, []byte(message), p.privateKey)
+	return hex.EncodeToString(signed[:sign.Overhead]), nil
+}
+
+type ecdsaSigner struct {
+	privateKey *btcec.PrivateKey
+}
+
+// NewEcdsaSigner return signer for compressed secp256k1 public keys
+func NewEcdsaSigner(privateKey *btcec.PrivateKey) Signer {
+	return &ecdsaSigner{privateKey: privateKey}
+}
+
+func (p *ecdsaSigner) Algorithm() string {
+	return confidential.AlgorithmEcdsa
+}
+
+func (p *ecdsaSigner) PublicKey() string {
+	return hex.EncodeToString(p.privateKey.PubKey().SerializeCompressed())
+}
+
+// Sign return DER signature of message double sha256
+func (p *ecdsaSigner) Sign(message string) (string, error) {
+	signature := ecdsa.Sign(p.privateKey, chainhash.DoubleHashB([]byte(message)))
+	return hex.EncodeToString(signature.Serialize()), nil
+}
+
+type schnorrSigner struct {
+	privateKey *btcec.PrivateKey
+}
+
+// NewSchnorrSigner return BIP-340 signer for x-only public keys
+func NewSchnorrSigner(privateKey *btcec.PrivateKey) Signer {
+	return &schnorrSigner{privateKey: privateKey}
+}
+
+func (p *schnorrSigner) Algorithm() string {
+	return confidential.AlgorithmSchnorr
+}
+
+func (p *schnorrSigner) PublicKey() string {
+	return hex.EncodeToString(schnorr.SerializePubKey(p.privateKey.PubKey()))
+}
+
+// Sign return signature of message sha256
+func (p *schnorrSigner) Sign(message string) (string, error) {
+	hash := sha256.Sum256([]byte(message))
+	signature, err := schnorr.Sign(p.privateKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signature.Serialize()), nil
+}
+
+type legacySigner struct {
+	algorithm  string
+	privateKey *btcec.PrivateKey
+	address    string
+}
+
+// NewLegacySigner return Bitcoin signed message signer for P2PKH address of privateKey.
+// Algorithm is mainnet, testnet3, signet or regtest.
+func NewLegacySigner(algorithm string, privateKey *btcec.PrivateKey) (Signer, error) {
+	params, ok := legacyNetworks[algorithm]
+	if !ok {
+		return nil, confidential.ErrUnknownAlgorithm
+	}
+	address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(privateKey.PubKey().SerializeCompressed()), params)
+	if err != nil {
+		return nil, err
+	}
+	return &legacySigner{
+		algorithm:  algorithm,
+		privateKey: privateKey,
+		address:    address.EncodeAddress(),
+	}, nil
+}
+
+func (p *legacySigner) Algorithm() string {
+	return p.algorithm
+}
+
+func (p *legacySigner) PublicKey() string {
+	return p.address
+}
+
+// Sign return base64 compact signature
+func (p *legacySigner) Sign(message string) (string, error) {
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Bitcoin Signed Message:\n")
+	wire.WriteVarString(&buf, 0, message)
+
+	signature, err := ecdsa.SignCompact(p.privateKey, chainhash.DoubleHashB(buf.Bytes()), true)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+type bip322Signer struct {
+	privateKey *btcec.PrivateKey
+	address    string
+	taproot    bool
+}
+
+// NewBip322Signer return BIP-322 simple signature signer for P2WPKH address of privateKey
+func NewBip322Signer(privateKey *btcec.PrivateKey, params *chaincfg.Params) (Signer, error) {
+	address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(privateKey.PubKey().SerializeCompressed()), params)
+	if err != nil {
+		return nil, err
+	}
+	return &bip322Signer{
+		privateKey: privateKey,
+		address:    address.EncodeAddress(),
+	}, nil
+}
+
+// NewBip322TaprootSigner return BIP-322 simple signature signer for P2TR key path address of privateKey
+func NewBip322TaprootSigner(privateKey *btcec.PrivateKey, params *chaincfg.Params) (Signer, error) {
+	outputKey := txscript.ComputeTaprootKeyNoScript(privateKey.PubKey())
+	address, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
+	if err != nil {
+		return nil, err
+	}
+	return &bip322Signer{
+		privateKey: privateKey,
+		address:    address.EncodeAddress(),
+		taproot:    true,
+	}, nil
+}
+
+func (p *bip322Signer) Algorithm() string {
+	return confidential.AlgorithmBip322
+}
+
+func (p *bip322Signer) PublicKey() string {
+	return p.address
+}
+
+// Sign return base64 encoded witness stack of to_sign transaction
+func (p *bip322Signer) Sign(message string) (string, error) {
+	toSign, pkScript, err := confidential.Bip322ToSign(p.address, message)
+	if err != nil {
+		return "", err
+	}
+	prevOuts := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	sigHashes := txscript.NewTxSigHashes(toSign, prevOuts)
+
+	var witness wire.TxWitness
+	if p.taproot {
+		signature, err := txscript.RawTxInTaprootSignature(toSign, sigHashes, 0, 0, pkScript, nil, txscript.SigHashDefault, p.privateKey)
+		if err != nil {
+			return "", err
+		}
+		witness = wire.TxWitness{signature}
+	} else {
+		witness, err = txscript.WitnessSignature(toSign, sigHashes, 0, 0, pkScript, txscript.SigHashAll, p.privateKey, true)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	err = wire.WriteVarInt(&buf, 0, uint64(len(witness)))
+	if err != nil {
+		return "", err
+	}
+	for _, item := range witness {
+		err = wire.WriteVarBytes(&buf, 0, item)
+		if err != nil {
+			return "", err
+		}
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
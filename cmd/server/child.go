@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/ipc"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func startChildSoroban(ctx context.Context, options soroban.Options, childID int) {
+	executablePath, err := os.Executable()
+	if err != nil {
+		log.WithError(err).
+			Fatal("Failed to get current executable path")
+	}
+
+	if !strings.HasPrefix(executablePath, "/") {
+		executablePath = path.Join("./", executablePath)
+	}
+	if !fileExists(executablePath) {
+		log.Fatal("Soroban executable not found")
+	}
+
+	dhtServerMode := ""
+	if options.P2P.DHTServerMode {
+		dhtServerMode = "--p2pDHTServerMode"
+	}
+
+	// p2p seed and tor control password are sent with a pipe to keep them out of the process list and environment
+	var secrets soroban.ChildSecrets
+	if options.P2P.Seed != "auto" {
+		secrets.P2PSeed = options.P2P.Seed
+	}
+	secrets.TorControlPassword = options.Tor.ControlPassword
+	if len(secrets.TorControlPassword) == 0 {
+		secrets.TorControlPassword = os.Getenv(soroban.TorControlPasswordEnv)
+	}
+	var input []byte
+	secretsFd := "0"
+	if !secrets.Empty() {
+		input, err = json.Marshal(secrets)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to marshal child secrets")
+		}
+		secretsFd = strconv.Itoa(ipc.InputFd)
+	}
+
+	// options are sent as flags, environment must not override them in child
+	go ipc.StartProcessDaemonWithEnv(ctx, fmt.Sprintf("soroban-child-%d", childID),
+		executablePath, childEnvironment(os.Environ()), input,
+		"serve",
+		// "--config", optionsc.Soroban.Config,
+		"--domain", options.Soroban.Domain,
+		"--adminPort", strconv.Itoa(options.Soroban.AdminPort),
+		"--ipcChildID", strconv.Itoa(childID),
+		"--ipcNatsHost", options.IPC.NatsHost,
+		"--ipcNatsPort", strconv.Itoa(options.IPC.NatsPort),
+		"--ipcSecretsFd", secretsFd,
+		"--p2pBootstrap", options.P2P.Bootstrap,
+		"--p2pRoom", options.P2P.Room,
+		"--p2pTransport", options.P2P.Transport,
+		"--p2pHostname", options.P2P.Hostname,
+		"--p2pListenPort", strconv.Itoa(options.P2P.ListenPort+childID),
+		"--p2pLowWater", strconv.Itoa(options.P2P.LowWater),
+		"--p2pHighWater", strconv.Itoa(options.P2P.HighWater),
+		fmt.Sprintf("--p2pMDNS=%t", options.P2P.MDNS),
+		"--p2pPeerstoreFile", options.P2P.PeerstoreFile,
+		"--p2pMessageFormat", options.P2P.MessageFormat,
+		"--p2pCompression", options.P2P.Compression,
+		"--p2pShards", strconv.Itoa(options.P2P.Shards),
+		"--p2pShardSubscribe", options.P2P.ShardSubscribe,
+		"--p2pShardMiss", options.P2P.ShardMiss,
+		"--gossipD", strconv.Itoa(options.Gossip.D),
+		"--gossipDlo", strconv.Itoa(options.Gossip.Dlo),
+		"--gossipDhi", strconv.Itoa(options.Gossip.Dhi),
+		"--gossipDout", strconv.Itoa(options.Gossip.Dout),
+		"--gossipDscore", strconv.Itoa(options.Gossip.Dscore),
+		"--gossipDlazy", strconv.Itoa(options.Gossip.Dlazy),
+		"--gossipPrunePeers", strconv.Itoa(options.Gossip.PrunePeers),
+		"--gossipLimit", strconv.Itoa(options.Gossip.Limit),
+		fmt.Sprintf("--gossipPeerScore=%t", options.Gossip.PeerScore),
+		"--torControl", options.Tor.ControlAddress,
+		"--torSocks", options.Tor.SocksAddress,
+		"--torDNS", options.Tor.DNSAddress,
+		"--torDataDir", options.Tor.DataDir,
+		"--heartbeatInterval", options.Heartbeat.Interval.String(),
+		"--heartbeatStartupTimeout", options.Heartbeat.StartupTimeout.String(),
+		"--heartbeatTimeout", options.Heartbeat.Timeout.String(),
+		"--heartbeatRecovery", options.Heartbeat.Recovery,
+		"--log", log.GetLevel().String(),
+		dhtServerMode, // Must be the last flag
+	)
+
+}
+
+// childEnvironment return environ without soroban variables
+func childEnvironment(environ []string) []string {
+	result := make([]string, 0, len(environ))
+	for _, value := range environ {
+		if strings.HasPrefix(value, envPrefix) {
+			continue
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return err == nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+	"code.samourai.io/wallet/samourai-soroban/services"
+
+	gjson "github.com/gorilla/rpc/json"
+	"golang.org/x/net/proxy"
+)
+
+const (
+	defaultRPCURL    = "http://localhost:4242/rpc"
+	defaultAdminPort = 4243
+	rpcTimeout       = 2 * time.Minute
+)
+
+type rpcClient struct {
+	url    string
+	client http.Client
+}
+
+// newRPCClient return json-rpc client, socks proxy is used for onion urls
+func newRPCClient(url, socks string) (*rpcClient, error) {
+	client := http.Client{Timeout: rpcTimeout}
+	if len(socks) > 0 {
+		dialer, err := proxy.SOCKS5("tcp", socks, nil, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{Dial: dialer.Dial}
+	}
+	return &rpcClient{
+		url:    url,
+		client: client,
+	}, nil
+}
+
+func newAdminClient(port int) *rpcClient {
+	client, _ := newRPCClient(fmt.Sprintf("http://localhost:%d/rpc", port), "")
+	return client
+}
+
+func (p *rpcClient) Call(method string, args, result interface{}) error {
+	body, err := gjson.EncodeClientRequest(method, args)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return gjson.DecodeClientResponse(resp.Body, result)
+}
+
+func addClientFlags(fs *flag.FlagSet) (*string, *string) {
+	url := fs.String("url", defaultRPCURL, "Soroban json-rpc url")
+	socks := fs.String("socks", "", "Socks proxy address for onion urls (host:port)")
+	return url, socks
+}
+
+func runPeers(args []string) error {
+	fs := commandFlagSet("peers")
+	adminPort := fs.Int("adminPort", defaultAdminPort, "Admin json-rpc port on localhost")
+	jsonOutput := fs.Bool("json", false, "Print full p2p status as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var status p2p.Status
+	err := newAdminClient(*adminPort).Call("admin.P2P", &services.AdminArgs{}, &status)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		return printJSON(status)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDIRECTION\tLATENCY\tADDRESS")
+	for _, peer := range status.Peers {
+		addr := ""
+		if len(peer.Addrs) > 0 {
+			addr = peer.Addrs[0]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.0fms\t%s\n", peer.ID, peer.Direction, peer.LatencyMs, addr)
+	}
+	return w.Flush()
+}
+
+func runDir(args []string) error {
+	if len(args) == 0 {
+		commandFlagSet("dir").Usage()
+		return errors.New("dir command is required (list, add, remove)")
+	}
+
+	name := args[0]
+	fs := newFlagSet("dir "+name, "[flags] <name> [entry]", "Directory client")
+	url, socks := addClientFlags(fs)
+	limit := fs.Int("limit", 0, "Limit listed entries (list)")
+	mode := fs.String("mode", "default", "Entry time to live mode (add)")
+	pin := fs.String("pin", "", "Node public key, list response signature is verified (list)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	client, err := newRPCClient(*url, *socks)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return errors.New("name is required")
+		}
+		var result api.DirectoryEntriesResponse
+		err := client.Call(api.MethodList, &api.DirectoryEntries{
+			Name:  fs.Arg(0),
+			Limit: *limit,
+		}, &result)
+		if err != nil {
+			return err
+		}
+		if len(*pin) > 0 {
+			if err := result.Verify(*pin, api.DefaultResponseMaxAge); err != nil {
+				return fmt.Errorf("list response not verified: %w", err)
+			}
+		}
+		for _, entry := range result.Entries {
+			fmt.Println(entry)
+		}
+		return nil
+
+	case "add", "remove":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return errors.New("name and entry are required")
+		}
+		var result api.Response
+		method := map[string]string{"add": api.MethodAdd, "remove": api.MethodRemove}[name]
+		err := client.Call(method, &api.DirectoryEntry{
+			Name:  fs.Arg(0),
+			Entry: fs.Arg(1),
+			Mode:  *mode,
+		}, &result)
+		if err != nil {
+			return err
+		}
+		if result.Status != "success" {
+			return fmt.Errorf("%s failed: %s", name, result.Status)
+		}
+		return nil
+
+	default:
+		commandFlagSet("dir").Usage()
+		return fmt.Errorf("unknown dir command: %s", name)
+	}
+}
+
+func runSnapshot(args []string) error {
+	fs := commandFlagSet("snapshot")
+	adminPort := fs.Int("adminPort", defaultAdminPort, "Admin json-rpc port on localhost")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("snapshot file is required")
+	}
+
+	var snapshot services.AdminSnapshot
+	err := newAdminClient(*adminPort).Call("admin.Snapshot", &services.AdminArgs{}, &snapshot)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fs.Arg(0), data, 0600)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d keys written to %s\n", len(snapshot.Entries), fs.Arg(0))
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := commandFlagSet("restore")
+	adminPort := fs.Int("adminPort", defaultAdminPort, "Admin json-rpc port on localhost")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("snapshot file is required")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var snapshot services.AdminSnapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return err
+	}
+
+	var result api.Response
+	err = newAdminClient(*adminPort).Call("admin.Restore", &snapshot, &result)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d keys restored\n", len(snapshot.Entries))
+	return nil
+}
+
+func printJSON(obj interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(obj)
+}
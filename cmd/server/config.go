@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+	"code.samourai.io/wallet/samourai-soroban/server"
+
+	"gopkg.in/yaml.v2"
+)
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		commandFlagSet("config").Usage()
+		return errors.New("config command is required (validate, dump)")
+	}
+
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "dump":
+		return runConfigDump(args[1:])
+	default:
+		commandFlagSet("config").Usage()
+		return fmt.Errorf("unknown config command: %s", args[0])
+	}
+}
+
+func parseConfig(name string, args []string) (soroban.Options, error) {
+	fs := newFlagSet("config "+name, "[flags]", "Options precedence: defaults < configuration file < environment ("+envPrefix+"*) < flags.")
+	return loadOptions(fs, args)
+}
+
+func runConfigValidate(args []string) error {
+	options, err := parseConfig("validate", args)
+	if err != nil {
+		return err
+	}
+	if err := validateFiles(options); err != nil {
+		return err
+	}
+	fmt.Println("Configuration is valid")
+	return nil
+}
+
+// validateFiles check files referenced by options
+func validateFiles(options soroban.Options) error {
+	if len(options.Soroban.Confidential) > 0 {
+		data, err := os.ReadFile(options.Soroban.Confidential)
+		if err != nil {
+			return err
+		}
+		var config confidential.SorobanConfig
+		if err := config.Parse(data); err != nil {
+			return fmt.Errorf("invalid confidential file %s: %w", options.Soroban.Confidential, err)
+		}
+	}
+
+	clientAuths := []string{options.Soroban.ClientAuth}
+	for _, listener := range options.Listeners {
+		switch listener.Type {
+		case soroban.ListenerOnion, soroban.ListenerTCP, soroban.ListenerUnix:
+		default:
+			return fmt.Errorf("invalid listener %s type: %s", listener.Name, listener.Type)
+		}
+		clientAuths = append(clientAuths, listener.ClientAuth)
+	}
+	for _, filename := range clientAuths {
+		if len(filename) == 0 {
+			continue
+		}
+		config, err := server.ClientAuthLoad(filename)
+		if err == nil {
+			_, err = config.PublicKeys()
+		}
+		if err != nil {
+			return fmt.Errorf("invalid client auth file %s: %w", filename, err)
+		}
+	}
+	return nil
+}
+
+// redactOptions hide seeds and passwords, auto and onion values are kept
+func redactOptions(options soroban.Options) soroban.Options {
+	options.Soroban.Seed = redact(options.Soroban.Seed)
+	if options.Soroban.SigningSeed != soroban.SigningSeedOnion {
+		options.Soroban.SigningSeed = redact(options.Soroban.SigningSeed)
+	}
+	if options.P2P.Seed != "auto" {
+		options.P2P.Seed = redact(options.P2P.Seed)
+	}
+	options.Tor.ControlPassword = redact(options.Tor.ControlPassword)
+
+	// listeners are copied, options slice is not modified
+	listeners := options.Listeners
+	options.Listeners = nil
+	for _, listener := range listeners {
+		listener.Seed = redact(listener.Seed)
+		options.Listeners = append(options.Listeners, listener)
+	}
+	return options
+}
+
+func redact(value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	return "********"
+}
+
+func runConfigDump(args []string) error {
+	options, err := parseConfig("dump", args)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(redactOptions(options))
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
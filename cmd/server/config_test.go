@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+
+	"gopkg.in/yaml.v2"
+)
+
+func Test_redactOptions(t *testing.T) {
+	secrets := []string{
+		strings.Repeat("a1", 32),
+		strings.Repeat("b2", 32),
+		strings.Repeat("c3", 32),
+		strings.Repeat("d4", 32),
+		"tor-secret",
+	}
+	options := soroban.DefaultOptions
+	options.Soroban.Seed = secrets[0]
+	options.Soroban.SigningSeed = secrets[1]
+	options.P2P.Seed = secrets[2]
+	options.Listeners = []soroban.ListenerInfo{{Name: "onion", Type: soroban.ListenerOnion, Seed: secrets[3]}}
+	options.Tor.ControlPassword = secrets[4]
+
+	data, err := yaml.Marshal(redactOptions(options))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("redactOptions() output contains %s", secret)
+		}
+	}
+	if options.Listeners[0].Seed != secrets[3] {
+		t.Error("redactOptions() must not modify options listeners")
+	}
+
+	options.Soroban.SigningSeed = soroban.SigningSeedOnion
+	options.P2P.Seed = "auto"
+	redacted := redactOptions(options)
+	if redacted.Soroban.SigningSeed != soroban.SigningSeedOnion || redacted.P2P.Seed != "auto" {
+		t.Errorf("redactOptions() = %s, %s", redacted.Soroban.SigningSeed, redacted.P2P.Seed)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+)
+
+func addLogFlags(fs *flag.FlagSet, options *soroban.Options) {
+	fs.StringVar(&options.LogLevel, "log", options.LogLevel, "Log level (default info)")
+	fs.StringVar(&options.LogFile, "logfile", options.LogFile, "Log file (default -)")
+}
+
+func addServeFlags(fs *flag.FlagSet, options *soroban.Options) {
+	addLogFlags(fs, options)
+
+	fs.StringVar(&options.Soroban.Config, "config", options.Soroban.Config, "Yaml configuration file for soroban")
+	fs.StringVar(&options.Soroban.Confidential, "confidential", options.Soroban.Confidential, "Yaml configuration file for confidential keys")
+	fs.StringVar(&options.Soroban.ClientAuth, "clientAuth", options.Soroban.ClientAuth, "Yaml configuration file for onion v3 authorized clients")
+	fs.StringVar(&options.Soroban.Domain, "domain", options.Soroban.Domain, "Directory Domain")
+	fs.StringVar(&options.Soroban.Seed, "seed", options.Soroban.Seed, "Onion private key seed")
+
+	fs.BoolVar(&options.Soroban.WithTor, "withTor", options.Soroban.WithTor, "Hidden service enabled (default false)")
+	fs.StringVar(&options.Soroban.Hostname, "hostname", options.Soroban.Hostname, "server address (default localhost)")
+	fs.IntVar(&options.Soroban.Port, "port", options.Soroban.Port, "Server port (default 4242)")
+
+	fs.StringVar(&options.Soroban.DirectoryType, "directoryType", options.Soroban.DirectoryType, "Directory Type (default, redis, memory)")
+	fs.StringVar(&options.Soroban.Announce, "announce", options.Soroban.Announce, "Soroban key for node annouce")
+	fs.DurationVar(&options.Soroban.AnnounceInterval, "announceInterval", options.Soroban.AnnounceInterval, "Delay between node announces")
+	fs.StringVar(&options.Soroban.AnnounceMode, "announceMode", options.Soroban.AnnounceMode, "Node announces time to live mode (fast, short, normal, long)")
+	fs.BoolVar(&options.Soroban.AnnounceUnsigned, "announceUnsigned", options.Soroban.AnnounceUnsigned, "Accept announces without signature, or not bound to their url")
+	fs.StringVar(&options.Soroban.AnnounceKeys, "announceKeys", options.Soroban.AnnounceKeys, "Hex ed25519 keys allowed to sign announces of non-onion urls, comma separated")
+	fs.StringVar(&options.Soroban.TLSCertFile, "tlsCert", options.Soroban.TLSCertFile, "TLS certificate file for clearnet listener")
+	fs.StringVar(&options.Soroban.TLSKeyFile, "tlsKey", options.Soroban.TLSKeyFile, "TLS key file for clearnet listener")
+	fs.BoolVar(&options.Soroban.TLSSelfSigned, "tlsSelfSigned", options.Soroban.TLSSelfSigned, "TLS with self-signed certificate, saved to tlsCert/tlsKey if set")
+	fs.StringVar(&options.Soroban.UnixSocket, "unixSocket", options.Soroban.UnixSocket, "Unix socket path for local clients")
+	fs.StringVar(&options.Soroban.UnixMode, "unixMode", options.Soroban.UnixMode, "Unix socket file mode (octal)")
+	fs.DurationVar(&options.Soroban.SignatureWindow, "signatureWindow", options.Soroban.SignatureWindow, "Maximum delta between signed requests timestamp and server time")
+	fs.IntVar(&options.Soroban.ReplayCacheSize, "replayCacheSize", options.Soroban.ReplayCacheSize, "Number of signed requests remembered to reject replays (0 to disable)")
+	fs.StringVar(&options.Soroban.SigningSeed, "signingSeed", options.Soroban.SigningSeed, "Node signing key seed for directory responses ("+soroban.SigningSeedOnion+" to use onion seed)")
+	fs.IntVar(&options.Soroban.AdminPort, "adminPort", options.Soroban.AdminPort, "Admin json-rpc port on localhost (0 to disable)")
+
+	fs.StringVar(&options.P2P.Seed, "p2pSeed", options.P2P.Seed, "P2P Onion private key seed")
+	fs.StringVar(&options.P2P.IdentityFile, "p2pIdentityFile", options.P2P.IdentityFile, "P2P identity file, created if missing (encrypted with "+p2p.IdentityPassphraseEnv+")")
+	fs.StringVar(&options.P2P.Bootstrap, "p2pBootstrap", options.P2P.Bootstrap, "P2P bootstrap")
+	fs.StringVar(&options.P2P.Transport, "p2pTransport", options.P2P.Transport, "P2P transport (tor, tcp, quic, tcp+tor)")
+	fs.StringVar(&options.P2P.Hostname, "p2pHostname", options.P2P.Hostname, "P2P listen address for clearnet transports")
+	fs.IntVar(&options.P2P.ListenPort, "p2pListenPort", options.P2P.ListenPort, "P2P Listen Port")
+	fs.IntVar(&options.P2P.LowWater, "p2pLowWater", options.P2P.LowWater, "P2P Connection Low Watermark")
+	fs.IntVar(&options.P2P.HighWater, "p2pHighWater", options.P2P.HighWater, "P2P Connection High Watermark")
+	fs.StringVar(&options.P2P.Room, "p2pRoom", options.P2P.Room, "P2P Room")
+	fs.BoolVar(&options.P2P.DHTServerMode, "p2pDHTServerMode", options.P2P.DHTServerMode, "P2P DHT Server Mode")
+	fs.BoolVar(&options.P2P.MDNS, "p2pMDNS", options.P2P.MDNS, "P2P local peer discovery with mDNS")
+	fs.StringVar(&options.P2P.PeerstoreFile, "p2pPeerstoreFile", options.P2P.PeerstoreFile, "Peerstore file (default -)")
+	fs.StringVar(&options.P2P.MessageFormat, "p2pMessageFormat", options.P2P.MessageFormat, "P2P message format (auto, envelope, legacy)")
+	fs.StringVar(&options.P2P.Compression, "p2pCompression", options.P2P.Compression, "P2P message compression (zstd, none)")
+	fs.IntVar(&options.P2P.Shards, "p2pShards", options.P2P.Shards, "P2P shard topics count (0 for single room topic)")
+	fs.StringVar(&options.P2P.ShardSubscribe, "p2pShardSubscribe", options.P2P.ShardSubscribe, "P2P subscribed shards, comma separated (default all)")
+	fs.StringVar(&options.P2P.ShardMiss, "p2pShardMiss", options.P2P.ShardMiss, "P2P list policy for keys outside subscribed shards (forward, local)")
+
+	fs.IntVar(&options.Gossip.D, "gossipD", options.Gossip.D, "Gossip D")
+	fs.IntVar(&options.Gossip.Dlo, "gossipDlo", options.Gossip.Dlo, "Gossip Dlo")
+	fs.IntVar(&options.Gossip.Dhi, "gossipDhi", options.Gossip.Dhi, "Gossip Dhi")
+	fs.IntVar(&options.Gossip.Dout, "gossipDout", options.Gossip.Dout, "Gossip Dout")
+	fs.IntVar(&options.Gossip.Dscore, "gossipDscore", options.Gossip.Dscore, "Gossip Dscore")
+	fs.IntVar(&options.Gossip.Dlazy, "gossipDlazy", options.Gossip.Dlazy, "Gossip Dlazy")
+	fs.IntVar(&options.Gossip.PrunePeers, "gossipPrunePeers", options.Gossip.PrunePeers, "Gossip PrunePeers")
+	fs.IntVar(&options.Gossip.Limit, "gossipLimit", options.Gossip.Limit, "Gossip Limit")
+	fs.BoolVar(&options.Gossip.PeerScore, "gossipPeerScore", options.Gossip.PeerScore, "Gossip peer scoring")
+
+	fs.StringVar(&options.Tor.ControlAddress, "torControl", options.Tor.ControlAddress, "External tor control address (host:port), embedded tor is used if empty")
+	fs.StringVar(&options.Tor.ControlPassword, "torControlPassword", options.Tor.ControlPassword, "External tor control password, cookie auth is used if available (or "+soroban.TorControlPasswordEnv+")")
+	fs.StringVar(&options.Tor.SocksAddress, "torSocks", options.Tor.SocksAddress, "External tor socks address (host:port), queried from control port if empty")
+	fs.StringVar(&options.Tor.DataDir, "torDataDir", options.Tor.DataDir, "Embedded tor persistent data directory, temporary directories are used if empty")
+	fs.StringVar(&options.Tor.DNSAddress, "torDNS", options.Tor.DNSAddress, "External tor DNS address (host:port), required for p2p with external tor")
+
+	fs.DurationVar(&options.Heartbeat.Interval, "heartbeatInterval", options.Heartbeat.Interval, "P2P heartbeat publish interval")
+	fs.DurationVar(&options.Heartbeat.StartupTimeout, "heartbeatStartupTimeout", options.Heartbeat.StartupTimeout, "P2P heartbeat timeout before first heartbeat")
+	fs.DurationVar(&options.Heartbeat.Timeout, "heartbeatTimeout", options.Heartbeat.Timeout, "P2P heartbeat timeout")
+	fs.StringVar(&options.Heartbeat.Recovery, "heartbeatRecovery", options.Heartbeat.Recovery, "P2P recovery actions on heartbeat timeout, comma separated (rebootstrap, reconnect, restart, exit)")
+
+	fs.StringVar(&options.IPC.Subject, "ipcSubject", options.IPC.Subject, "IPC communication subject")
+	fs.IntVar(&options.IPC.ChildID, "ipcChildID", options.IPC.ChildID, "IPC child ID")
+	fs.IntVar(&options.IPC.SecretsFd, "ipcSecretsFd", options.IPC.SecretsFd, "IPC secrets file descriptor (used by child processes)")
+	fs.IntVar(&options.IPC.ChildProcessCount, "ipcChildProcessCount", options.IPC.ChildProcessCount, "Spawn child process")
+	fs.StringVar(&options.IPC.NatsHost, "ipcNatsHost", options.IPC.NatsHost, "IPC NATS host")
+	fs.IntVar(&options.IPC.NatsPort, "ipcNatsPort", options.IPC.NatsPort, "IPC nats port")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"code.samourai.io/wallet/samourai-soroban/server"
+)
+
+func runGenKey(args []string) error {
+	fs := commandFlagSet("genkey")
+	prefix := fs.String("prefix", "", "Onion address prefix (base32)")
+	count := fs.Int("count", 10, "Limit generated keys (0 for no limits)")
+	output := fs.String("output", "keys", "Output directory for generated keys")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(*prefix) == 0 {
+		*prefix = fs.Arg(0)
+	}
+	if len(*prefix) == 0 {
+		fs.Usage()
+		return errors.New("prefix is required")
+	}
+	return server.GenKey(*prefix, *count, *output)
+}
+
+func runExportHS(args []string) error {
+	fs := commandFlagSet("export-hs")
+	seed := fs.String("seed", "", "Onion private key seed")
+	output := fs.String("output", "hs_ed25519_secret_key", "Hidden service secret key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(*seed) == 0 {
+		fs.Usage()
+		return errors.New("seed is required")
+	}
+
+	data, err := server.ExportHiddenServiceSecret(*seed)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*output, data, 0600)
+}
+
+func runGenClientAuth(args []string) error {
+	fs := commandFlagSet("gen-client-auth")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return server.GenClientAuth(fs.Arg(0))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+)
+
+// envPrefix of environment variables overriding options, followed by flag name in upper snake case (SOROBAN_P2P_BOOTSTRAP)
+const envPrefix = "SOROBAN_"
+
+// flags not configurable from environment
+var envIgnored = map[string]bool{
+	"version": true,
+}
+
+// optionsLoader apply options with precedence: defaults < configuration file < environment < flags
+type optionsLoader struct {
+	fs      *flag.FlagSet
+	options *soroban.Options
+	flags   map[string]string
+}
+
+// newOptionsLoader parse args with server flags, explicit flags are kept to be applied last
+func newOptionsLoader(fs *flag.FlagSet, args []string) (*optionsLoader, error) {
+	options := soroban.DefaultOptions
+	addServeFlags(fs, &options)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return &optionsLoader{
+		fs:      fs,
+		options: &options,
+		flags:   flags,
+	}, nil
+}
+
+// Load return options from defaults, configuration file, environment and flags
+func (p *optionsLoader) Load() (soroban.Options, error) {
+	config, ok := p.flags["config"]
+	if !ok {
+		config = os.Getenv(envName("config"))
+	}
+
+	*p.options = soroban.DefaultOptions
+	if err := p.options.LoadFile(config); err != nil {
+		return *p.options, fmt.Errorf("configuration file %s: %w", config, err)
+	}
+
+	var errs []error
+	p.fs.VisitAll(func(f *flag.Flag) {
+		if envIgnored[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if err := p.fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("environment %s: %w", envName(f.Name), err))
+		}
+	})
+	if len(errs) > 0 {
+		return *p.options, errors.Join(errs...)
+	}
+
+	for name, value := range p.flags {
+		if err := p.fs.Set(name, value); err != nil {
+			return *p.options, err
+		}
+	}
+
+	p.options.Soroban.Config = config
+	p.options.Version = Version
+	return *p.options, nil
+}
+
+// envName return environment variable name from flag name (p2pDHTServerMode: SOROBAN_P2P_DHT_SERVER_MODE)
+func envName(name string) string {
+	runes := []rune(name)
+	var result strings.Builder
+	result.WriteString(envPrefix)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				result.WriteRune('_')
+			}
+		}
+		result.WriteRune(unicode.ToUpper(r))
+	}
+	return result.String()
+}
+
+// LoadValid return validated options
+func (p *optionsLoader) LoadValid() (soroban.Options, error) {
+	options, err := p.Load()
+	if err != nil {
+		return options, err
+	}
+	if err := options.Validate(); err != nil {
+		return options, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return options, nil
+}
+
+// loadOptions parse server flags and return validated options
+func loadOptions(fs *flag.FlagSet, args []string) (soroban.Options, error) {
+	loader, err := newOptionsLoader(fs, args)
+	if err != nil {
+		return soroban.DefaultOptions, err
+	}
+	return loader.LoadValid()
+}
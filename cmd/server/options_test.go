@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+)
+
+func Test_OptionsLoaderPrecedence(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "soroban.yml")
+	err := os.WriteFile(config, []byte("soroban:\n  port: 1000\n  domain: file\np2p:\n  room: file\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOROBAN_CONFIG", config)
+	t.Setenv("SOROBAN_PORT", "2000")
+	t.Setenv("SOROBAN_DOMAIN", "env")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader, err := newOptionsLoader(fs, []string{"--port", "3000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Soroban.Hostname != soroban.DefaultOptions.Soroban.Hostname {
+		t.Errorf("Load() default hostname = %s", options.Soroban.Hostname)
+	}
+	if options.P2P.Room != "file" {
+		t.Errorf("Load() file room = %s", options.P2P.Room)
+	}
+	if options.Soroban.Domain != "env" {
+		t.Errorf("Load() env domain = %s", options.Soroban.Domain)
+	}
+	if options.Soroban.Port != 3000 {
+		t.Errorf("Load() flag port = %d", options.Soroban.Port)
+	}
+	if options.Soroban.Config != config {
+		t.Errorf("Load() config = %s", options.Soroban.Config)
+	}
+
+	// reload keeps explicit flags over updated environment
+	t.Setenv("SOROBAN_PORT", "2001")
+	t.Setenv("SOROBAN_DOMAIN", "env2")
+	options, err = loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Soroban.Port != 3000 || options.Soroban.Domain != "env2" {
+		t.Errorf("Load() reload = %d, %s", options.Soroban.Port, options.Soroban.Domain)
+	}
+}
+
+func Test_envName(t *testing.T) {
+	tests := map[string]string{
+		"p2pBootstrap":     "SOROBAN_P2P_BOOTSTRAP",
+		"p2pDHTServerMode": "SOROBAN_P2P_DHT_SERVER_MODE",
+		"torControl":       "SOROBAN_TOR_CONTROL",
+		"gossipD":          "SOROBAN_GOSSIP_D",
+	}
+	for name, want := range tests {
+		if got := envName(name); got != want {
+			t.Errorf("envName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func Test_childEnvironment(t *testing.T) {
+	environ := childEnvironment([]string{"PATH=/bin", "SOROBAN_PORT=2000", "SOROBAN_TOR_CONTROL_PASSWORD=secret", "HOME=/root"})
+	if len(environ) != 2 || environ[0] != "PATH=/bin" || environ[1] != "HOME=/root" {
+		t.Errorf("childEnvironment() = %v", environ)
+	}
+}
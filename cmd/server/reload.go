@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// editors write configuration files with several events, reload once they are done
+const reloadDebounce = time.Second
+
+// reloader reload options on SIGHUP and configuration file change
+type reloader struct {
+	loader   *optionsLoader
+	options  soroban.Options
+	onReload func(previous, options soroban.Options)
+}
+
+func (p *reloader) Run(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	var events chan fsnotify.Event
+	config := p.options.Soroban.Config
+	if len(config) > 0 {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.WithError(err).Error("Failed to create config watcher")
+		} else {
+			defer watcher.Close()
+
+			// watch directory, editors replace the file on save
+			err = watcher.Add(filepath.Dir(config))
+			if err != nil {
+				log.WithError(err).WithField("Filename", config).Error("Failed to watch config file")
+			}
+			events = watcher.Events
+		}
+	}
+
+	// pending file change reload
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-sighup:
+			log.Info("SIGHUP received, reloading options")
+			p.reload()
+
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(config) || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			debounce.Reset(reloadDebounce)
+
+		case <-debounce.C:
+			log.WithField("Filename", config).Info("Config file changed, reloading options")
+			p.reload()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload apply reloadable options, invalid configurations are ignored
+func (p *reloader) reload() {
+	options, err := p.loader.LoadValid()
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			log.Error(line)
+		}
+		log.Error("Failed to reload options, previous options are kept")
+		return
+	}
+
+	if restart := p.options.RestartRequired(options); len(restart) > 0 {
+		log.WithField("Options", strings.Join(restart, ",")).Warning("Restart required to apply options")
+	}
+
+	previous := p.options
+	p.options = previous.WithReloadable(options)
+	p.onReload(previous, p.options)
+	log.Info("Options reloaded")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+	"code.samourai.io/wallet/samourai-soroban/server"
+	"code.samourai.io/wallet/samourai-soroban/services"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// delay between child processes startup
+const childStartDelay = 5 * time.Second
+
+func runServe(args []string) error {
+	fs := commandFlagSet("serve")
+	version := fs.Bool("version", false, "Print version and exit")
+	loader, err := newOptionsLoader(fs, args)
+	if err != nil {
+		return err
+	}
+	if *version {
+		printVersionExit()
+	}
+	options, err := loader.LoadValid()
+	if err != nil {
+		return err
+	}
+	if err := setupLog(options); err != nil {
+		return err
+	}
+	// child processes receive secrets from a file descriptor
+	options, err = options.WithChildSecrets()
+	if err != nil {
+		return err
+	}
+	services.ApplyOptions(context.Background(), options)
+	// options as configured, for reload comparison
+	configured := options
+
+	spawnChildren := options.IPC.ChildProcessCount > 0 && options.IPC.ChildID == 0
+	if spawnChildren {
+		// resolve p2p identity once for all child processes
+		seed, err := p2p.ResolveSeed(options.P2P)
+		if err != nil {
+			return fmt.Errorf("invalid p2p identity: %w", err)
+		}
+		options.P2P.Seed = seed
+		options.P2P.IdentityFile = ""
+	}
+
+	ctx := context.Background()
+	ctx = soroban.WithTorContext(ctx)
+
+	ctx, sorobanServer := server.New(ctx, options)
+	if sorobanServer == nil {
+		// soroban is in child mode
+		// keep the process alive
+		log.Info("Soroban started in child mode")
+		<-ctx.Done()
+		return nil
+	}
+
+	if spawnChildren {
+		log.Info("Starting child process")
+		for i := 0; i < options.IPC.ChildProcessCount; i++ {
+			startChildSoroban(ctx, options, i+1)
+			<-time.After(childStartDelay)
+		}
+	}
+
+	err = services.RegisterAll(ctx, sorobanServer)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	log.Info("Staring soroban...")
+	if len(options.Listeners) > 0 {
+		err = sorobanServer.StartListeners(ctx, options.Listeners)
+	} else if options.Soroban.WithTor {
+		err = sorobanServer.StartWithTor(ctx, options.Soroban.Hostname, options.Soroban.Port, options.Soroban.Seed)
+	} else {
+		err = sorobanServer.Start(ctx, options.Soroban.Hostname, options.Soroban.Port)
+	}
+	if err != nil {
+		return err
+	}
+	defer sorobanServer.Stop(ctx)
+
+	sorobanServer.WaitForStart(ctx)
+
+	if len(sorobanServer.ID()) != 0 {
+		log.Infof("Soroban started: http://%s.onion", sorobanServer.ID())
+	}
+	if len(options.Listeners) == 0 && (!options.Soroban.WithTor || (options.Soroban.IPv4 && options.Soroban.Hostname != "0.0.0.0")) {
+		scheme := "http"
+		if options.Soroban.TLSSelfSigned || len(options.Soroban.TLSCertFile) > 0 {
+			scheme = "https"
+		}
+		log.Infof("Soroban started: %s://%s:%d/", scheme, options.Soroban.Hostname, options.Soroban.Port)
+	}
+
+	var announces []string
+	if len(sorobanServer.ID()) > 0 {
+		announces = append(announces, fmt.Sprintf("http://%s.onion", sorobanServer.ID()))
+	}
+	if options.Soroban.IPv4 {
+		announces = append(announces, fmt.Sprintf("http://%s:%d", options.Soroban.Hostname, options.Soroban.Port))
+	}
+	stopAnnounce := startAnnounce(ctx, sorobanServer, options, announces)
+
+	reload := reloader{
+		loader:  loader,
+		options: configured,
+		onReload: func(previous, options soroban.Options) {
+			services.ApplyOptions(ctx, options)
+			if err := services.BroadcastReload(ctx, options); err != nil {
+				log.WithError(err).Error("Failed to send reload to child processes")
+			}
+			if previous.Soroban.Announce != options.Soroban.Announce ||
+				previous.Soroban.AnnounceInterval != options.Soroban.AnnounceInterval ||
+				previous.Soroban.AnnounceMode != options.Soroban.AnnounceMode {
+				stopAnnounce()
+				stopAnnounce = startAnnounce(ctx, sorobanServer, options, announces)
+			}
+		},
+	}
+	go reload.Run(ctx)
+
+	<-ctx.Done()
+	return nil
+}
+
+// startAnnounce announce node urls signed with node onion key, returned function stop announces
+func startAnnounce(ctx context.Context, sorobanServer *server.Soroban, options soroban.Options, announces []string) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	if len(options.Soroban.Announce) == 0 {
+		return cancel
+	}
+
+	signer := sorobanServer.AnnounceSigner()
+	if signer == nil && !options.Soroban.AnnounceUnsigned {
+		log.Warning("Announces are not signed (no onion or signing key) and will be rejected")
+	}
+	if signer != nil {
+		// other nodes must allow the key for non-onion urls
+		log.WithField("PublicKey", fmt.Sprintf("%x", signer.Public())).Info("Announces signed")
+	}
+
+	go services.StartAnnounce(ctx, services.AnnounceOptions{
+		Key:      options.Soroban.Announce,
+		Version:  Version,
+		URLs:     announces,
+		Interval: options.Soroban.AnnounceInterval,
+		Mode:     options.Soroban.AnnounceMode,
+		Capabilities: api.AnnounceCapabilities{
+			Methods: services.Methods(),
+			P2P:     len(options.P2P.Bootstrap) > 0 || options.P2P.MDNS,
+			Limits: api.AnnounceLimits{
+				SignatureWindow: int64(options.Soroban.SignatureWindow.Seconds()),
+				MaxTTL:          int64(options.TTL.Long.Seconds()),
+			},
+		},
+		Signer: signer,
+		Load:   sorobanServer.Load,
+	})
+	return cancel
+}
+
+func WaitForExit(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan bool, 1)
+
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigs
+		soroban.Shutdown(ctx)
+		fmt.Println("Soroban exited")
+		done <- true
+	}()
+
+	select {
+	case <-done:
+		return
+
+	case <-ctx.Done():
+		return
+	}
+}
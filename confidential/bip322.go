@@ -0,0 +1,197 @@
+package confidential
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	verifier "github.com/bitonicnl/verify-signed-message/pkg"
+)
+
+// BIP-322 generic signed message format
+// https://github.com/bitcoin/bips/blob/master/bip-0322.mediawiki
+
+var bip322Tag = []byte("BIP0322-signed-message")
+
+// networks used to decode bip322 addresses, signet addresses are decoded as testnet
+var bip322Networks = []*chaincfg.Params{
+	&chaincfg.MainNetParams,
+	&chaincfg.TestNet3Params,
+	&chaincfg.RegressionNetParams,
+}
+
+// maximum size of witness stack items
+const bip322MaxWitnessItemSize = 10000
+
+// bip322MessageHash return the tagged hash of message
+func bip322MessageHash(message string) []byte {
+	return chainhash.TaggedHash(bip322Tag, []byte(message))[:]
+}
+
+// bip322ToSpend return the virtual transaction spent by the signature
+func bip322ToSpend(pkScript []byte, message string) (*wire.MsgTx, error) {
+	scriptSig, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(bip322MessageHash(message)).
+		Script()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(0)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: 0xffffffff},
+		SignatureScript:  scriptSig,
+		Sequence:         0,
+	})
+	tx.AddTxOut(wire.NewTxOut(0, pkScript))
+	return tx, nil
+}
+
+// bip322ToSign return the virtual transaction signed with witness
+func bip322ToSign(toSpend *wire.MsgTx, witness wire.TxWitness) *wire.MsgTx {
+	tx := wire.NewMsgTx(0)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: toSpend.TxHash(), Index: 0},
+		Witness:          witness,
+		Sequence:         0,
+	})
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return tx
+}
+
+// Bip322ToSign return the unsigned to_sign transaction of message for address and the to_spend output script,
+// signers set the input witness of to_sign.
+func Bip322ToSign(address, message string) (*wire.MsgTx, []byte, error) {
+	addr, _, err := decodeBip322Address(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	toSpend, err := bip322ToSpend(pkScript, message)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bip322ToSign(toSpend, nil), pkScript, nil
+}
+
+// decodeBip322Address return address and network, mainnet, testnet (and signet) and regtest are supported
+func decodeBip322Address(address string) (btcutil.Address, *chaincfg.Params, error) {
+	for _, params := range bip322Networks {
+		addr, err := btcutil.DecodeAddress(address, params)
+		if err == nil && addr.IsForNet(params) {
+			return addr, params, nil
+		}
+	}
+	return nil, nil, errors.New("invalid bip322 address")
+}
+
+// verifyBip322Signature check simple (witness stack) or full (to_sign transaction) bip322 signature.
+// Legacy signed messages are accepted for P2PKH addresses.
+func verifyBip322Signature(address, message, signature string) error {
+	addr, params, err := decodeBip322Address(address)
+	if err != nil {
+		return malformed(ErrMalformedKey, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return malformed(ErrMalformedSignature, err)
+	}
+
+	if _, ok := addr.(*btcutil.AddressPubKeyHash); ok && len(data) == verifier.ExpectedSignatureLength {
+		return verifyLegacySignature(address, message, signature, params)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return malformed(ErrMalformedKey, err)
+	}
+	toSpend, err := bip322ToSpend(pkScript, message)
+	if err != nil {
+		return err
+	}
+
+	toSign, err := parseBip322Full(data)
+	if err == nil {
+		if !bip322ValidToSign(toSign, toSpend) {
+			return ErrBadSignature
+		}
+	} else {
+		witness, err := parseBip322Witness(data)
+		if err != nil {
+			return malformed(ErrMalformedSignature, err)
+		}
+		toSign = bip322ToSign(toSpend, witness)
+	}
+
+	if err := verifyBip322Transaction(pkScript, toSign); err != nil {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// parseBip322Full decode a full signature, a consensus encoded to_sign transaction
+func parseBip322Full(data []byte) (*wire.MsgTx, error) {
+	var tx wire.MsgTx
+	reader := bytes.NewReader(data)
+	err := tx.Deserialize(reader)
+	if err != nil {
+		return nil, err
+	}
+	if reader.Len() != 0 {
+		return nil, errors.New("trailing data")
+	}
+	return &tx, nil
+}
+
+// bip322ValidToSign return true if toSign spend toSpend to a single OP_RETURN output
+func bip322ValidToSign(toSign, toSpend *wire.MsgTx) bool {
+	if len(toSign.TxIn) != 1 || toSign.TxIn[0].PreviousOutPoint != (wire.OutPoint{Hash: toSpend.TxHash(), Index: 0}) {
+		return false
+	}
+	return len(toSign.TxOut) == 1 && toSign.TxOut[0].Value == 0 && bytes.Equal(toSign.TxOut[0].PkScript, []byte{txscript.OP_RETURN})
+}
+
+// parseBip322Witness decode a simple signature, a consensus encoded witness stack
+func parseBip322Witness(data []byte) (wire.TxWitness, error) {
+	reader := bytes.NewReader(data)
+	count, err := wire.ReadVarInt(reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 || count > uint64(len(data)) {
+		return nil, errors.New("invalid witness size")
+	}
+	witness := make(wire.TxWitness, 0, count)
+	for i := uint64(0); i < count; i++ {
+		item, err := wire.ReadVarBytes(reader, 0, bip322MaxWitnessItemSize, "witness")
+		if err != nil {
+			return nil, err
+		}
+		witness = append(witness, item)
+	}
+	if reader.Len() != 0 {
+		return nil, errors.New("trailing data")
+	}
+	return witness, nil
+}
+
+// verifyBip322Transaction execute pkScript with to_sign input
+func verifyBip322Transaction(pkScript []byte, toSign *wire.MsgTx) error {
+	prevOuts := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	engine, err := txscript.NewEngine(pkScript, toSign, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(toSign, prevOuts), 0, prevOuts)
+	if err != nil {
+		return err
+	}
+	return engine.Execute()
+}
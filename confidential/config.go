@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v2"
@@ -20,14 +21,88 @@ const (
 	AlgorithmEcdsa    = "ecdsa"
 	AlgorithmTestnet3 = "testnet3"
 	AlgorithmMainnet  = "mainnet"
+	AlgorithmSignet   = "signet"
+	AlgorithmRegtest  = "regtest"
+	AlgorithmSchnorr  = "schnorr"
+	AlgorithmBip322   = "bip322"
 )
 
+var (
+	ErrPublicKeyNotAllowed = errors.New("publicKey not allowed")
+	ErrPublicKeyNotValid   = errors.New("publicKey not valid at this time")
+)
+
+// ConfidentialKey is an authorized public key, valid between optional NotBefore and NotAfter
+type ConfidentialKey struct {
+	Algorithm string    `yaml:"algorithm"`
+	PublicKey string    `yaml:"publickey"`
+	NotBefore time.Time `yaml:"not_before"`
+	NotAfter  time.Time `yaml:"not_after"`
+}
+
+// ValidAt return true if key is valid at t
+func (p ConfidentialKey) ValidAt(t time.Time) bool {
+	if !p.NotBefore.IsZero() && t.Before(p.NotBefore) {
+		return false
+	}
+	if !p.NotAfter.IsZero() && t.After(p.NotAfter) {
+		return false
+	}
+	return true
+}
+
 type ConfidentialEntry struct {
-	Prefix       string `yaml:"prefix"`
-	Algorithm    string `yaml:"algorithm"`
-	PublicKey    string `yaml:"publickey"`
-	Confidential bool   `yaml:"confidential"`
-	ReadOnly     bool   `yaml:"readonly"`
+	Prefix       string            `yaml:"prefix"`
+	Algorithm    string            `yaml:"algorithm"`
+	PublicKey    string            `yaml:"publickey"`
+	Keys         []ConfidentialKey `yaml:"keys"`
+	Confidential bool              `yaml:"confidential"`
+	ReadOnly     bool              `yaml:"readonly"`
+
+	// policy, see Permission
+	Read       string        `yaml:"read"`
+	Add        string        `yaml:"add"`
+	Remove     string        `yaml:"remove"`
+	Allow      []string      `yaml:"allow"`
+	Deny       []string      `yaml:"deny"`
+	MaxEntries int           `yaml:"maxentries"`
+	MaxTTL     time.Duration `yaml:"maxttl"`
+}
+
+// AllKeys return authorized keys, including legacy publickey.
+// Keys algorithm default to entry algorithm.
+func (p ConfidentialEntry) AllKeys() []ConfidentialKey {
+	var result []ConfidentialKey
+	if len(p.PublicKey) > 0 {
+		result = append(result, ConfidentialKey{
+			Algorithm: p.Algorithm,
+			PublicKey: p.PublicKey,
+		})
+	}
+	for _, key := range p.Keys {
+		if len(key.Algorithm) == 0 {
+			key.Algorithm = p.Algorithm
+		}
+		result = append(result, key)
+	}
+	return result
+}
+
+// AuthorizedKey return key matching publicKey and valid at t
+func (p ConfidentialEntry) AuthorizedKey(publicKey string, t time.Time) (ConfidentialKey, error) {
+	err := ErrPublicKeyNotAllowed
+	for _, key := range p.AllKeys() {
+		if len(publicKey) == 0 || key.PublicKey != publicKey {
+			continue
+		}
+		if !key.ValidAt(t) {
+			// an other key entry may be valid during rotation
+			err = ErrPublicKeyNotValid
+			continue
+		}
+		return key, nil
+	}
+	return ConfidentialKey{}, err
 }
 
 type SorobanConfig struct {
@@ -157,7 +232,12 @@ func match(pattern string, value string) bool {
 	return false
 }
 
-func GetConfidentialInfo(directory, publicKey string) ConfidentialEntry {
+// GetConfidentialInfo return the rule matching directory.
+// When several rules match, the first one with publicKey authorized is returned.
+// If publicKey is not authorized by any matching rule, the first matching rule is returned with
+// ErrPublicKeyNotAllowed or ErrPublicKeyNotValid: callers must deny operations with signed permission.
+// Anonymous requests get the first matching rule, an empty rule is returned if none match.
+func GetConfidentialInfo(directory, publicKey string) (ConfidentialEntry, error) {
 	var entries []ConfidentialEntry
 
 	// find all matching prefix
@@ -166,21 +246,23 @@ func GetConfidentialInfo(directory, publicKey string) ConfidentialEntry {
 			entries = append(entries, entry)
 		}
 	}
+	if len(entries) == 0 {
+		return ConfidentialEntry{}, nil
+	}
+	if len(publicKey) == 0 {
+		return entries[0], nil
+	}
 
-	var result ConfidentialEntry
-	// find first matching publicKey if exists
+	now := time.Now()
+	result := ErrPublicKeyNotAllowed
 	for _, entry := range entries {
-		if entry.PublicKey == publicKey {
-			result = entry
+		_, err := entry.AuthorizedKey(publicKey, now)
+		if err == nil {
+			return entry, nil
+		}
+		if errors.Is(err, ErrPublicKeyNotValid) {
+			result = err
 		}
 	}
-
-	// some matched prefix exists but with no matching publicKey
-	if len(result.Prefix) == 0 && len(entries) > 0 {
-		result = entries[0] // use first entry
-	}
-	if len(publicKey) == 0 && len(entries) > 0 {
-		return entries[0]
-	}
-	return result
+	return entries[0], result
 }
@@ -0,0 +1,87 @@
+package confidential
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_GetConfidentialInfo(t *testing.T) {
+	config := `
+confidential:
+  - prefix: rotation.*
+    algorithm: ecdsa
+    confidential: true
+    keys:
+      - publickey: old
+        not_after: 2000-01-02T00:00:00Z
+      - publickey: current
+        not_before: 2000-01-01T00:00:00Z
+      - publickey: next
+        algorithm: nacl
+        not_before: 2999-01-01T00:00:00Z
+  - prefix: legacy.*
+    algorithm: ecdsa
+    publickey: first
+    readonly: true
+  - prefix: legacy.*
+    algorithm: ecdsa
+    publickey: second
+    readonly: true
+`
+	var sorobanConfig SorobanConfig
+	if err := sorobanConfig.Parse([]byte(config)); err != nil {
+		t.Fatal(err)
+	}
+	previous := DefaultSorobanConfig
+	DefaultSorobanConfig = sorobanConfig
+	defer func() { DefaultSorobanConfig = previous }()
+
+	tests := []struct {
+		name      string
+		directory string
+		publicKey string
+		wantKey   string
+		wantErr   error
+	}{
+		{"current", "rotation.a", "current", "current", nil},
+		{"expired", "rotation.a", "old", "", ErrPublicKeyNotValid},
+		{"not yet valid", "rotation.a", "next", "", ErrPublicKeyNotValid},
+		{"unknown", "rotation.a", "unknown", "", ErrPublicKeyNotAllowed},
+		{"anonymous", "rotation.a", "", "", ErrPublicKeyNotAllowed},
+		{"legacy first", "legacy.a", "first", "first", nil},
+		{"legacy second", "legacy.a", "second", "second", nil},
+		{"legacy unknown", "legacy.a", "unknown", "", ErrPublicKeyNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ruleErr := GetConfidentialInfo(tt.directory, tt.publicKey)
+			if len(info.Prefix) == 0 {
+				t.Fatalf("GetConfidentialInfo() no matching rule")
+			}
+			// anonymous requests are evaluated with the first matching rule
+			wantRuleErr := tt.wantErr
+			if len(tt.publicKey) == 0 {
+				wantRuleErr = nil
+			}
+			if !errors.Is(ruleErr, wantRuleErr) {
+				t.Errorf("GetConfidentialInfo() error = %v, want %v", ruleErr, wantRuleErr)
+			}
+			key, err := info.AuthorizedKey(tt.publicKey, time.Now())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AuthorizedKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if key.PublicKey != tt.wantKey {
+				t.Errorf("AuthorizedKey() = %v, want %v", key.PublicKey, tt.wantKey)
+			}
+		})
+	}
+
+	if info, err := GetConfidentialInfo("other", "current"); len(info.Prefix) != 0 || err != nil {
+		t.Errorf("GetConfidentialInfo() = %v, %v, want no rule", info, err)
+	}
+	info, _ := GetConfidentialInfo("rotation.a", "")
+	if keys := info.AllKeys(); keys[2].Algorithm != AlgorithmNacl || keys[0].Algorithm != AlgorithmEcdsa {
+		t.Errorf("AllKeys() = %v", keys)
+	}
+}
@@ -0,0 +1,201 @@
+package confidential
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Directory operations
+const (
+	OperationList   = "list"
+	OperationAdd    = "add"
+	OperationRemove = "remove"
+)
+
+// Permissions of a directory operation
+const (
+	// PermissionAnyone allow anonymous requests
+	PermissionAnyone = "anyone"
+	// PermissionSigned require a signature from an authorized key
+	PermissionSigned = "signed"
+	// PermissionOwner require a signature from the key which added the entry (remove only)
+	PermissionOwner = "owner"
+	// PermissionNone deny all requests
+	PermissionNone = "none"
+)
+
+// Signature versions of directory requests
+const (
+	// SignatureV1 sign name, timestamp and entry
+	SignatureV1 = 1
+	// SignatureV2 also sign rpc method and client nonce
+	SignatureV2 = 2
+)
+
+// maximum delta between signed request timestamp and server time
+var signatureTimestampDelta = int64(DefaultSignatureWindow)
+
+func signatureWindow() time.Duration {
+	return time.Duration(atomic.LoadInt64(&signatureTimestampDelta))
+}
+
+func setSignatureWindow(window time.Duration) {
+	atomic.StoreInt64(&signatureTimestampDelta, int64(window))
+}
+
+var (
+	ErrOperationNotAllowed = errors.New("operation not allowed")
+	ErrPublicKeyDenied     = errors.New("publicKey denied")
+	ErrSignatureRequired   = errors.New("signature required")
+	ErrTimestampRange      = errors.New("timestamp not in time range")
+	ErrNotOwner            = errors.New("entry not owned by publicKey")
+	ErrQuotaExceeded       = errors.New("directory quota exceeded")
+	ErrSignatureVersion    = errors.New("unknown signature version")
+)
+
+// SignedRequest is a directory request with optional signature
+type SignedRequest struct {
+	PublicKey string
+	Algorithm string
+	Signature string
+	// Timestamp in nanoseconds
+	Timestamp int64
+	// Message is the signed message
+	Message string
+	// Version of signature, SignatureV1 if not set
+	Version int
+}
+
+// RequestMessage return the message signed for a directory request.
+// Version 1 message is name.timestamp[.entry], version 2 is v2.method.name.timestamp.nonce[.entry]
+func RequestMessage(version int, method, name string, timestamp int64, nonce string, entry ...string) string {
+	var parts []string
+	switch version {
+	case SignatureV2:
+		parts = []string{"v2", method, name, fmt.Sprintf("%d", timestamp), nonce}
+	default:
+		parts = []string{name, fmt.Sprintf("%d", timestamp)}
+	}
+	parts = append(parts, entry...)
+	return strings.Join(parts, ".")
+}
+
+// Signed return true if request has signature
+func (p SignedRequest) Signed() bool {
+	return len(p.PublicKey) > 0 && len(p.Signature) > 0
+}
+
+// Permission return rule permission for operation.
+// Legacy confidential (signed list) and readonly (signed add and remove) are used when not set.
+func (p ConfidentialEntry) Permission(operation string) string {
+	var permission string
+	var signed bool
+	switch operation {
+	case OperationList:
+		permission, signed = p.Read, p.Confidential
+	case OperationAdd:
+		permission, signed = p.Add, p.ReadOnly
+	case OperationRemove:
+		permission, signed = p.Remove, p.ReadOnly
+	default:
+		return PermissionNone
+	}
+
+	if len(permission) > 0 {
+		return permission
+	}
+	// legacy rules without keys don't check signatures
+	if signed && len(p.AllKeys()) > 0 {
+		return PermissionSigned
+	}
+	return PermissionAnyone
+}
+
+// Authorize check request permission for operation.
+// Returned signer is the public key of a verified signature, empty for anonymous requests.
+func (p ConfidentialEntry) Authorize(operation string, request SignedRequest) (string, error) {
+	permission := p.Permission(operation)
+	if permission == PermissionOwner && operation != OperationRemove {
+		permission = PermissionNone
+	}
+	if permission == PermissionNone {
+		return "", ErrOperationNotAllowed
+	}
+	if request.Signed() && contains(p.Deny, request.PublicKey) {
+		return "", ErrPublicKeyDenied
+	}
+
+	signer, err := p.verifyRequest(permission, request)
+	if permission == PermissionAnyone && len(p.Allow) == 0 {
+		// requests with invalid signature are anonymous
+		return signer, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(p.Allow) > 0 && !contains(p.Allow, signer) {
+		return "", ErrPublicKeyNotAllowed
+	}
+	return signer, nil
+}
+
+// verifyRequest check request signature with an authorized key for signed permission, or with request public key
+func (p ConfidentialEntry) verifyRequest(permission string, request SignedRequest) (string, error) {
+	if !request.Signed() {
+		return "", ErrSignatureRequired
+	}
+	if request.Version != 0 && request.Version != SignatureV1 && request.Version != SignatureV2 {
+		return "", ErrSignatureVersion
+	}
+	if err := checkTimestamp(request.Timestamp); err != nil {
+		return "", err
+	}
+
+	key := ConfidentialKey{
+		Algorithm: request.Algorithm,
+		PublicKey: request.PublicKey,
+	}
+	if permission == PermissionSigned {
+		var err error
+		key, err = p.AuthorizedKey(request.PublicKey, time.Now())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := verifyKeySignature(key, request.PublicKey, request.Message, request.Algorithm, request.Signature)
+	if err != nil {
+		return "", err
+	}
+	return request.PublicKey, nil
+}
+
+// TimeToLive return ttl limited by rule ceiling
+func (p ConfidentialEntry) TimeToLive(ttl time.Duration) time.Duration {
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		return p.MaxTTL
+	}
+	return ttl
+}
+
+func checkTimestamp(timestamp int64) error {
+	window := signatureWindow()
+	now := time.Now().UTC()
+	t := time.Unix(0, timestamp).UTC()
+	if !t.After(now.Add(-window)) || !t.Before(now.Add(window)) {
+		return ErrTimestampRange
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package confidential
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/nacl/sign"
+)
+
+// naclRequest return a request signed with a new nacl key
+func naclRequest(t *testing.T, message string) SignedRequest {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return naclSign(publicKey, privateKey, message)
+}
+
+func naclSign(publicKey *[32]byte, privateKey *[64]byte, message string) SignedRequest {
+	timestamp := time.Now().UnixNano()
+	message = fmt.Sprintf("%s.%d", message, timestamp)
+	signed := sign.Sign(nil, []byte(message), privateKey)
+	return SignedRequest{
+		PublicKey: hex.EncodeToString(publicKey[:]),
+		Algorithm: AlgorithmNacl,
+		Signature: hex.EncodeToString(signed[:sign.Overhead]),
+		Timestamp: timestamp,
+		Message:   message,
+	}
+}
+
+func Test_ConfidentialEntryPermission(t *testing.T) {
+	keys := []ConfidentialKey{{Algorithm: AlgorithmNacl, PublicKey: "key"}}
+	tests := []struct {
+		name      string
+		entry     ConfidentialEntry
+		operation string
+		want      string
+	}{
+		{"legacy confidential", ConfidentialEntry{Confidential: true, Keys: keys}, OperationList, PermissionSigned},
+		{"legacy confidential add", ConfidentialEntry{Confidential: true, Keys: keys}, OperationAdd, PermissionAnyone},
+		{"legacy readonly add", ConfidentialEntry{ReadOnly: true, Keys: keys}, OperationAdd, PermissionSigned},
+		{"legacy readonly remove", ConfidentialEntry{ReadOnly: true, Keys: keys}, OperationRemove, PermissionSigned},
+		{"legacy without keys", ConfidentialEntry{ReadOnly: true}, OperationRemove, PermissionAnyone},
+		{"policy", ConfidentialEntry{ReadOnly: true, Remove: PermissionOwner}, OperationRemove, PermissionOwner},
+		{"unknown operation", ConfidentialEntry{}, "other", PermissionNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Permission(tt.operation); got != tt.want {
+				t.Errorf("Permission() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ConfidentialEntryAuthorize(t *testing.T) {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized := naclSign(publicKey, privateKey, "name")
+	other := naclRequest(t, "name")
+
+	invalid := authorized
+	invalid.Message = "tampered"
+
+	expired := authorized
+	expired.Timestamp = time.Now().Add(-48 * time.Hour).UnixNano()
+
+	keys := []ConfidentialKey{{Algorithm: AlgorithmNacl, PublicKey: authorized.PublicKey}}
+
+	tests := []struct {
+		name       string
+		entry      ConfidentialEntry
+		operation  string
+		request    SignedRequest
+		wantSigner string
+		wantErr    error
+	}{
+		{"anonymous", ConfidentialEntry{}, OperationAdd, SignedRequest{}, "", nil},
+		{"anyone signed", ConfidentialEntry{}, OperationAdd, other, other.PublicKey, nil},
+		{"anyone invalid signature", ConfidentialEntry{}, OperationAdd, invalid, "", nil},
+		{"signed", ConfidentialEntry{Add: PermissionSigned, Keys: keys}, OperationAdd, authorized, authorized.PublicKey, nil},
+		{"signed anonymous", ConfidentialEntry{Add: PermissionSigned, Keys: keys}, OperationAdd, SignedRequest{}, "", ErrSignatureRequired},
+		{"signed other key", ConfidentialEntry{Add: PermissionSigned, Keys: keys}, OperationAdd, other, "", ErrPublicKeyNotAllowed},
+		{"signed timestamp", ConfidentialEntry{Add: PermissionSigned, Keys: keys}, OperationAdd, expired, "", ErrTimestampRange},
+		{"none", ConfidentialEntry{Read: PermissionNone}, OperationList, authorized, "", ErrOperationNotAllowed},
+		{"owner add", ConfidentialEntry{Add: PermissionOwner}, OperationAdd, authorized, "", ErrOperationNotAllowed},
+		{"owner remove", ConfidentialEntry{Remove: PermissionOwner}, OperationRemove, other, other.PublicKey, nil},
+		{"owner anonymous", ConfidentialEntry{Remove: PermissionOwner}, OperationRemove, SignedRequest{}, "", ErrSignatureRequired},
+		{"deny", ConfidentialEntry{Deny: []string{other.PublicKey}}, OperationAdd, other, "", ErrPublicKeyDenied},
+		{"allow", ConfidentialEntry{Allow: []string{other.PublicKey}}, OperationAdd, other, other.PublicKey, nil},
+		{"allow other", ConfidentialEntry{Allow: []string{other.PublicKey}}, OperationAdd, authorized, "", ErrPublicKeyNotAllowed},
+		{"allow anonymous", ConfidentialEntry{Allow: []string{other.PublicKey}}, OperationAdd, SignedRequest{}, "", ErrSignatureRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := tt.entry.Authorize(tt.operation, tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+			if signer != tt.wantSigner {
+				t.Errorf("Authorize() signer = %v, want %v", signer, tt.wantSigner)
+			}
+		})
+	}
+}
+
+func Test_ConfidentialEntryTimeToLive(t *testing.T) {
+	entry := ConfidentialEntry{MaxTTL: time.Minute}
+	if ttl := entry.TimeToLive(5 * time.Minute); ttl != time.Minute {
+		t.Errorf("TimeToLive() = %v", ttl)
+	}
+	if ttl := entry.TimeToLive(15 * time.Second); ttl != 15*time.Second {
+		t.Errorf("TimeToLive() = %v", ttl)
+	}
+}
+
+func Test_ConfidentialEntryAuthorizeWindow(t *testing.T) {
+	defer setSignatureWindow(DefaultSignatureWindow)
+
+	request := naclRequest(t, "name")
+	request.Timestamp = time.Now().Add(-10 * time.Minute).UnixNano()
+	entry := ConfidentialEntry{Add: PermissionOwner, Remove: PermissionOwner}
+
+	setSignatureWindow(time.Minute)
+	if _, err := entry.Authorize(OperationRemove, request); !errors.Is(err, ErrTimestampRange) {
+		t.Errorf("Authorize() error = %v", err)
+	}
+	request.Version = 3
+	request.Timestamp = time.Now().UnixNano()
+	if _, err := entry.Authorize(OperationRemove, request); !errors.Is(err, ErrSignatureVersion) {
+		t.Errorf("Authorize() version error = %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package confidential
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSignatureWindow is the default maximum delta between signed request timestamp and server time
+	DefaultSignatureWindow = 5 * time.Minute
+	// DefaultReplayCacheSize is the default number of signed requests remembered for replay protection
+	DefaultReplayCacheSize = 100000
+)
+
+var ErrReplayedRequest = errors.New("signed request already used")
+
+// ReplayCache remember signed requests until their timestamp is out of the signature window.
+// Requests are ordered by expiration, oldest requests are evicted when cache is full.
+type ReplayCache struct {
+	mtx    sync.Mutex
+	window time.Duration
+	size   int
+	seen   map[string]*list.Element
+	order  *list.List
+}
+
+type replayEntry struct {
+	key      string
+	expireOn time.Time
+}
+
+// NewReplayCache create a cache for size requests, size 0 disable the cache
+func NewReplayCache(window time.Duration, size int) *ReplayCache {
+	return &ReplayCache{
+		window: window,
+		size:   size,
+		seen:   make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// DefaultReplayCache is used for json-rpc signed requests
+var DefaultReplayCache = NewReplayCache(DefaultSignatureWindow, DefaultReplayCacheSize)
+
+// SetReplayProtection configure signature window and default replay cache size
+func SetReplayProtection(window time.Duration, size int) {
+	if window > 0 {
+		setSignatureWindow(window)
+	}
+	DefaultReplayCache.Configure(signatureWindow(), size)
+}
+
+// Configure update cache window and size, oldest requests are evicted if size is reduced
+func (p *ReplayCache) Configure(window time.Duration, size int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.window = window
+	p.size = size
+	p.evict(time.Now())
+}
+
+// Len return the number of remembered requests
+func (p *ReplayCache) Len() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.order.Len()
+}
+
+// Check return ErrReplayedRequest if request was already seen, request is remembered otherwise.
+// Request key is computed from public key and signed message, signature malleability can't bypass the cache.
+func (p *ReplayCache) Check(request SignedRequest) error {
+	if p == nil || !request.Signed() {
+		return nil
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.size <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	p.evict(now)
+
+	key := replayKey(request)
+	if element, ok := p.seen[key]; ok {
+		if element.Value.(replayEntry).expireOn.After(now) {
+			return ErrReplayedRequest
+		}
+		p.order.Remove(element)
+	}
+
+	p.seen[key] = p.insert(replayEntry{
+		key:      key,
+		expireOn: time.Unix(0, request.Timestamp).Add(p.window),
+	})
+	p.evict(now)
+	return nil
+}
+
+// insert entry ordered by expiration, requests are mostly received in timestamp order
+func (p *ReplayCache) insert(entry replayEntry) *list.Element {
+	element := p.order.Back()
+	for element != nil && element.Value.(replayEntry).expireOn.After(entry.expireOn) {
+		element = element.Prev()
+	}
+	if element == nil {
+		return p.order.PushFront(entry)
+	}
+	return p.order.InsertAfter(entry, element)
+}
+
+// evict remove expired requests and oldest requests over size
+func (p *ReplayCache) evict(now time.Time) {
+	for element := p.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(replayEntry)
+		if p.order.Len() <= p.size && entry.expireOn.After(now) {
+			break
+		}
+		p.order.Remove(element)
+		delete(p.seen, entry.key)
+		element = next
+	}
+}
+
+func replayKey(request SignedRequest) string {
+	hash := sha256.Sum256([]byte(request.PublicKey + "\x00" + request.Message))
+	return hex.EncodeToString(hash[:])
+}
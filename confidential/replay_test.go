@@ -0,0 +1,97 @@
+package confidential
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_ReplayCache(t *testing.T) {
+	cache := NewReplayCache(time.Minute, 2)
+
+	first := naclRequest(t, "first")
+	if err := cache.Check(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Check(first); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("Check() replay error = %v", err)
+	}
+
+	// signature is not part of the key
+	malleated := first
+	malleated.Signature = "00" + first.Signature
+	if err := cache.Check(malleated); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("Check() malleated error = %v", err)
+	}
+
+	if err := cache.Check(SignedRequest{}); err != nil {
+		t.Errorf("Check() unsigned error = %v", err)
+	}
+
+	// oldest request is evicted when full
+	cache.Check(naclRequest(t, "second"))
+	cache.Check(naclRequest(t, "third"))
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d", cache.Len())
+	}
+	if err := cache.Check(first); err != nil {
+		t.Errorf("Check() evicted error = %v", err)
+	}
+
+	// expired requests are removed
+	expired := naclRequest(t, "expired")
+	expired.Timestamp = time.Now().Add(-2 * time.Minute).UnixNano()
+	cache.Configure(time.Minute, 10)
+	cache.Check(expired)
+	if err := cache.Check(expired); err != nil {
+		t.Errorf("Check() expired error = %v", err)
+	}
+
+	cache.Configure(time.Minute, 0)
+	if cache.Len() != 0 {
+		t.Errorf("Len() disabled = %d", cache.Len())
+	}
+}
+
+func Test_ReplayCacheMixedExpiry(t *testing.T) {
+	cache := NewReplayCache(time.Minute, 10)
+
+	// expired requests received after a newer one must not use capacity
+	newer := naclRequest(t, "newer")
+	newer.Timestamp = time.Now().Add(30 * time.Second).UnixNano()
+	if err := cache.Check(newer); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		request := naclRequest(t, fmt.Sprintf("old%d", i))
+		request.Timestamp = time.Now().Add(-59*time.Second - 900*time.Millisecond).UnixNano()
+		if err := cache.Check(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cache.Len() != 4 {
+		t.Fatalf("Len() = %d", cache.Len())
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	cache.Check(naclRequest(t, "trigger"))
+	if cache.Len() != 2 {
+		t.Errorf("Len() after expiration = %d, want 2", cache.Len())
+	}
+	if err := cache.Check(newer); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("Check() newer replay error = %v", err)
+	}
+}
+
+func Test_RequestMessage(t *testing.T) {
+	if m := RequestMessage(0, "directory.Add", "name", 42, "nonce", "entry"); m != "name.42.entry" {
+		t.Errorf("RequestMessage() v1 = %s", m)
+	}
+	if m := RequestMessage(SignatureV1, "directory.List", "name", 42, ""); m != "name.42" {
+		t.Errorf("RequestMessage() v1 list = %s", m)
+	}
+	if m := RequestMessage(SignatureV2, "directory.Remove", "name", 42, "nonce", "entry"); m != "v2.directory.Remove.name.42.nonce.entry" {
+		t.Errorf("RequestMessage() v2 = %s", m)
+	}
+}
@@ -1,13 +1,18 @@
 package confidential
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"time"
 
 	"golang.org/x/crypto/nacl/sign"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -17,82 +22,90 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func toNaclPubKey(publicKey string) *[32]byte {
-	var result [32]byte
+var (
+	ErrUnknownAlgorithm   = errors.New("unknown signature algorithm")
+	ErrAlgorithmMismatch  = errors.New("signature algorithm not matching key algorithm")
+	ErrMalformedKey       = errors.New("malformed publicKey")
+	ErrMalformedSignature = errors.New("malformed signature")
+	ErrBadSignature       = errors.New("invalid signature")
+)
+
+// malformed wrap err with sentinel
+func malformed(sentinel, err error) error {
+	return fmt.Errorf("%w: %s", sentinel, err)
+}
+
+func toNaclPubKey(publicKey string) (*[32]byte, error) {
 	key, err := hex.DecodeString(publicKey)
 	if err != nil {
-		return nil
+		return nil, malformed(ErrMalformedKey, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: invalid length %d", ErrMalformedKey, len(key))
 	}
-	copy(result[:], key[:32])
-	return &result
+	var result [32]byte
+	copy(result[:], key)
+	return &result, nil
 }
 
 // VerifySignature check signature with publicKey and message
-// Support Nacl and Ecdsa Algorithms
+// publicKey must be an authorized key of info, valid now.
+// Support Nacl, Ecdsa, Schnorr, Bip322 and legacy signed messages Algorithms
 func VerifySignature(info ConfidentialEntry, publicKey, message, algorithm, signature string) error {
-	if len(info.Prefix) == 0 || len(info.Algorithm) == 0 || len(info.PublicKey) == 0 {
+	if len(info.Prefix) == 0 || len(info.AllKeys()) == 0 {
 		return nil
 	}
-	log.WithField("Info", info).Debug("Verify Signature")
-	switch info.Algorithm {
-	case AlgorithmNacl:
-		if info.Algorithm != algorithm {
-			return errors.New("algorithm not maching")
-		}
-		if info.PublicKey != publicKey {
-			return errors.New("publicKey not maching")
-		}
-
-		verified := verifyNaclSignature(publicKey, message, signature)
-		if !verified {
-			return errors.New("invalid singature")
-		}
-
-		log.Debug("Signature verified")
-		return nil
+	key, err := info.AuthorizedKey(publicKey, time.Now())
+	if err != nil {
+		return err
+	}
+	log.WithField("Prefix", info.Prefix).WithField("Key", key).Debug("Verify Signature")
 
-	case AlgorithmEcdsa:
-		if info.PublicKey != publicKey {
-			return errors.New("publicKey not maching")
-		}
+	return verifyKeySignature(key, publicKey, message, algorithm, signature)
+}
 
-		verified := verifyEcdsaSignature(publicKey, message, signature)
-		if !verified {
-			return errors.New("invalid singature")
-		}
+// verifyKeySignature check signature with key algorithm.
+// Request algorithm must match key algorithm when set, nacl requests must set the algorithm.
+func verifyKeySignature(key ConfidentialKey, publicKey, message, algorithm, signature string) error {
+	if key.Algorithm != algorithm && (len(algorithm) > 0 || key.Algorithm == AlgorithmNacl) {
+		return ErrAlgorithmMismatch
+	}
 
-		log.Debug("Signature verified")
-		return nil
+	var err error
+	switch key.Algorithm {
+	case AlgorithmNacl:
+		err = verifyNaclSignature(publicKey, message, signature)
+
+	case AlgorithmEcdsa:
+		err = verifyEcdsaSignature(publicKey, message, signature)
 
 	case AlgorithmTestnet3:
-		if info.PublicKey != publicKey {
-			return errors.New("publicKey not maching")
-		}
+		err = verifyTestnet3Signature(publicKey, message, signature)
 
-		verified := verifyTestnet3Signature(publicKey, message, signature)
-		if !verified {
-			return errors.New("invalid singature")
-		}
+	case AlgorithmMainnet:
+		err = verifyMainnetSignature(publicKey, message, signature)
 
-		log.Debug("Signature verified")
-		return nil
+	case AlgorithmSignet:
+		err = verifyLegacySignature(publicKey, message, signature, &chaincfg.SigNetParams)
 
-	case AlgorithmMainnet:
-		if info.PublicKey != publicKey {
-			return errors.New("publicKey not maching")
-		}
+	case AlgorithmRegtest:
+		err = verifyLegacySignature(publicKey, message, signature, &chaincfg.RegressionNetParams)
 
-		verified := verifyMainnetSignature(publicKey, message, signature)
-		if !verified {
-			return errors.New("invalid singature")
-		}
+	case AlgorithmSchnorr:
+		err = verifySchnorrSignature(publicKey, message, signature)
 
-		log.Debug("Signature verified")
-		return nil
+	case AlgorithmBip322:
+		err = verifyBip322Signature(publicKey, message, signature)
 
 	default:
-		return errors.New("unknown signature algorithm")
+		return ErrUnknownAlgorithm
+	}
+	if err != nil {
+		return err
 	}
+
+	log.Debug("Signature verified")
+	return nil
 }
 
 func signMessage(privateKey, message string) string {
@@ -111,60 +124,116 @@ func signMessage(privateKey, message string) string {
 	return hex.EncodeToString(signature.Serialize())
 }
 
-func verifyNaclSignature(publicKey, message, signature string) bool {
-	signedMessage, _ := hex.DecodeString(signature)
+func verifyNaclSignature(publicKey, message, signature string) error {
+	pubKey, err := toNaclPubKey(publicKey)
+	if err != nil {
+		return err
+	}
+	signedMessage, err := hex.DecodeString(signature)
+	if err != nil {
+		return malformed(ErrMalformedSignature, err)
+	}
+	if len(signedMessage) != sign.Overhead {
+		return fmt.Errorf("%w: invalid length %d", ErrMalformedSignature, len(signedMessage))
+	}
 	signedMessage = append(signedMessage, []byte(message)...)
 
-	_, verified := sign.Open(nil, signedMessage, toNaclPubKey(publicKey))
-	return verified
+	if _, verified := sign.Open(nil, signedMessage, pubKey); !verified {
+		return ErrBadSignature
+	}
+	return nil
 }
 
-func verifyEcdsaSignature(publicKey, message, signature string) bool {
+func verifyEcdsaSignature(publicKey, message, signature string) error {
 	pubKeyBytes, err := hex.DecodeString(publicKey)
 	if err != nil {
-		panic(err)
+		return malformed(ErrMalformedKey, err)
 	}
 	pubKey, err := btcec.ParsePubKey(pubKeyBytes)
 	if err != nil {
-		panic(err)
+		return malformed(ErrMalformedKey, err)
 	}
 
 	sigBytes, err := hex.DecodeString(signature)
 	if err != nil {
-		panic(err)
+		return malformed(ErrMalformedSignature, err)
 	}
 	sign, err := ecdsa.ParseSignature(sigBytes)
 	if err != nil {
-		panic(err)
+		return malformed(ErrMalformedSignature, err)
 	}
 
 	messageHash := chainhash.DoubleHashB([]byte(message))
 
-	return sign.Verify(messageHash, pubKey)
+	if !sign.Verify(messageHash, pubKey) {
+		return ErrBadSignature
+	}
+	return nil
 }
 
-func verifyTestnet3Signature(publicKey, message, signature string) bool {
-	result, err := verifier.VerifyWithChain(verifier.SignedMessage{
-		Address:   publicKey,
-		Message:   message,
-		Signature: signature,
-	}, &chaincfg.TestNet3Params)
+// verifySchnorrSignature check BIP-340 signature of message sha256 with x-only publicKey
+func verifySchnorrSignature(publicKey, message, signature string) error {
+	hash := sha256.Sum256([]byte(message))
+	return verifySchnorrHash(publicKey, hash[:], signature)
+}
+
+func verifySchnorrHash(publicKey string, hash []byte, signature string) error {
+	pubKeyBytes, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return malformed(ErrMalformedKey, err)
+	}
+	pubKey, err := schnorr.ParsePubKey(pubKeyBytes)
 	if err != nil {
-		return false
+		return malformed(ErrMalformedKey, err)
 	}
 
-	return result
+	sigBytes, err := hex.DecodeString(signature)
+	if err != nil {
+		return malformed(ErrMalformedSignature, err)
+	}
+	sign, err := schnorr.ParseSignature(sigBytes)
+	if err != nil {
+		return malformed(ErrMalformedSignature, err)
+	}
+
+	if !sign.Verify(hash, pubKey) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func verifyTestnet3Signature(publicKey, message, signature string) error {
+	return verifyLegacySignature(publicKey, message, signature, &chaincfg.TestNet3Params)
+}
+
+func verifyMainnetSignature(publicKey, message, signature string) error {
+	return verifyLegacySignature(publicKey, message, signature, &chaincfg.MainNetParams)
 }
 
-func verifyMainnetSignature(publicKey, message, signature string) bool {
+// verifyLegacySignature check Bitcoin signed message with address on network
+func verifyLegacySignature(publicKey, message, signature string, params *chaincfg.Params) error {
+	address, err := btcutil.DecodeAddress(publicKey, params)
+	if err != nil {
+		return malformed(ErrMalformedKey, err)
+	}
+	if !address.IsForNet(params) {
+		return fmt.Errorf("%w: address not for %s", ErrMalformedKey, params.Name)
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return malformed(ErrMalformedSignature, err)
+	}
+	if len(sigBytes) != verifier.ExpectedSignatureLength {
+		return fmt.Errorf("%w: invalid length %d", ErrMalformedSignature, len(sigBytes))
+	}
+
 	result, err := verifier.VerifyWithChain(verifier.SignedMessage{
 		Address:   publicKey,
 		Message:   message,
 		Signature: signature,
-	}, &chaincfg.MainNetParams)
-	if err != nil {
-		return false
+	}, params)
+	if err != nil || !result {
+		return ErrBadSignature
 	}
-
-	return result
+	return nil
 }
@@ -0,0 +1,61 @@
+package confidential
+
+import (
+	"errors"
+	"testing"
+)
+
+// checkVerifyError fail if err is not a typed signature error
+func checkVerifyError(t *testing.T, err error) {
+	if err == nil {
+		return
+	}
+	for _, sentinel := range []error{ErrMalformedKey, ErrMalformedSignature, ErrBadSignature} {
+		if errors.Is(err, sentinel) {
+			return
+		}
+	}
+	t.Errorf("untyped error: %v", err)
+}
+
+func Fuzz_verifyNaclSignature(f *testing.F) {
+	f.Add("0000000000000000000000000000000000000000000000000000000000000000", "message", "00")
+	f.Add("00", "", "")
+	f.Fuzz(func(t *testing.T, publicKey, message, signature string) {
+		checkVerifyError(t, verifyNaclSignature(publicKey, message, signature))
+	})
+}
+
+func Fuzz_verifyEcdsaSignature(f *testing.F) {
+	f.Add("024d1d2028d6a503c5d688425eddcb9a348696d606fb6d521b8a336de760d51e8e", "Hello, World!", "30440220046e86f0bff9639a893616e1db3abfa24cafa8818e7e47798c860d5982968ef502200241904a24128f6f73b8f5675368ff85992aa2b97bb40fe91ab361c96c62ca35")
+	f.Add("02", "", "30")
+	f.Fuzz(func(t *testing.T, publicKey, message, signature string) {
+		checkVerifyError(t, verifyEcdsaSignature(publicKey, message, signature))
+	})
+}
+
+func Fuzz_verifySchnorrSignature(f *testing.F) {
+	f.Add("F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9", "message", "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0")
+	f.Add("", "", "")
+	f.Fuzz(func(t *testing.T, publicKey, message, signature string) {
+		checkVerifyError(t, verifySchnorrSignature(publicKey, message, signature))
+	})
+}
+
+func Fuzz_verifyLegacySignature(f *testing.F) {
+	f.Add("mk7YZqsP6jEJ4XNqdDQEXYwR4umRKceddR", "Test", "IIQCJwCvFQ62E7JlOsozLbyjybqLE719G1hPxZcJBANGIxP7rtv5Rg9RFJ3gsBe19kbeFyKfaKqFGUIGbuZHORI=")
+	f.Add("1", "", "AA==")
+	f.Fuzz(func(t *testing.T, address, message, signature string) {
+		checkVerifyError(t, verifyMainnetSignature(address, message, signature))
+		checkVerifyError(t, verifyTestnet3Signature(address, message, signature))
+	})
+}
+
+func Fuzz_verifyBip322Signature(f *testing.F) {
+	f.Add("bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "Hello World", "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=")
+	f.Add("bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3", "Hello World", "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ==")
+	f.Add("bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "", "/////w==")
+	f.Fuzz(func(t *testing.T, address, message, signature string) {
+		checkVerifyError(t, verifyBip322Signature(address, message, signature))
+	})
+}
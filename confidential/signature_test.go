@@ -1,7 +1,18 @@
 package confidential
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 )
 
 func Test_signMessage(t *testing.T) {
@@ -40,7 +51,7 @@ func Test_verifyEcdsaSignature(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := verifyEcdsaSignature(tt.args.publicKey, tt.args.message, tt.args.signature); got != tt.want {
+			if got := verifyEcdsaSignature(tt.args.publicKey, tt.args.message, tt.args.signature) == nil; got != tt.want {
 				t.Errorf("verifyEcdsaSignature() = %v, want %v", got, tt.want)
 			}
 		})
@@ -65,9 +76,188 @@ func Test_verifyTestnet3Signature(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := verifyTestnet3Signature(tt.args.publicKey, tt.args.message, tt.args.signature); got != tt.want {
+			if got := verifyTestnet3Signature(tt.args.publicKey, tt.args.message, tt.args.signature) == nil; got != tt.want {
 				t.Errorf("verifyTestnet3Signature() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_verifySchnorrHash(t *testing.T) {
+	// BIP-340 test vectors
+	type args struct {
+		publicKey string
+		hash      string
+		signature string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"vector-0", args{"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9", "0000000000000000000000000000000000000000000000000000000000000000", "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0"}, true},
+		{"vector-1", args{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89", "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A"}, true},
+		{"wrong-hash", args{"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659", "0000000000000000000000000000000000000000000000000000000000000000", "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A"}, false},
+		{"malformed-key", args{"00", "0000000000000000000000000000000000000000000000000000000000000000", "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, _ := hex.DecodeString(tt.args.hash)
+			if got := verifySchnorrHash(tt.args.publicKey, hash, tt.args.signature) == nil; got != tt.want {
+				t.Errorf("verifySchnorrHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_verifySchnorrSignature(t *testing.T) {
+	wif, err := btcutil.DecodeWIF("L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := hex.EncodeToString(schnorr.SerializePubKey(wif.PrivKey.PubKey()))
+	hash := sha256.Sum256([]byte("Hello World"))
+	sig, err := schnorr.Sign(wif.PrivKey, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := hex.EncodeToString(sig.Serialize())
+
+	if err := verifySchnorrSignature(publicKey, "Hello World", signature); err != nil {
+		t.Errorf("verifySchnorrSignature() = %v", err)
+	}
+	if err := verifySchnorrSignature(publicKey, "Hello World!", signature); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("verifySchnorrSignature() wrong message = %v", err)
+	}
+}
+
+func Test_bip322MessageHash(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"", "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1"},
+		{"Hello World", "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(bip322MessageHash(tt.message)); got != tt.want {
+			t.Errorf("bip322MessageHash(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func Test_verifyBip322Signature(t *testing.T) {
+	// BIP-322 test vectors
+	type args struct {
+		address   string
+		message   string
+		signature string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"p2wpkh-empty", args{"bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "", "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="}, true},
+		{"p2wpkh", args{"bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "Hello World", "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="}, true},
+		{"p2wpkh-wrong-message", args{"bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "", "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="}, false},
+		{"p2tr", args{"bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3", "Hello World", "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ=="}, true},
+		{"p2tr-wrong-message", args{"bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3", "Hello", "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ=="}, false},
+		{"p2pkh-legacy", args{"mk7YZqsP6jEJ4XNqdDQEXYwR4umRKceddR", "Test", "IIQCJwCvFQ62E7JlOsozLbyjybqLE719G1hPxZcJBANGIxP7rtv5Rg9RFJ3gsBe19kbeFyKfaKqFGUIGbuZHORI="}, true},
+		{"invalid-address", args{"invalid", "Hello World", "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ=="}, false},
+		{"invalid-signature", args{"bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "Hello World", "AA=="}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyBip322Signature(tt.args.address, tt.args.message, tt.args.signature) == nil; got != tt.want {
+				t.Errorf("verifyBip322Signature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_verifyBip322SignatureFull(t *testing.T) {
+	address := "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+	simple := "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="
+
+	addr, _, err := decodeBip322Address(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, _ := txscript.PayToAddrScript(addr)
+	toSpend, _ := bip322ToSpend(pkScript, "Hello World")
+	data, _ := base64.StdEncoding.DecodeString(simple)
+	witness, err := parseBip322Witness(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	bip322ToSign(toSpend, witness).Serialize(&buf)
+	full := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	if err := verifyBip322Signature(address, "Hello World", full); err != nil {
+		t.Errorf("verifyBip322Signature() full = %v", err)
+	}
+	if err := verifyBip322Signature(address, "Hello", full); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("verifyBip322Signature() full wrong message = %v", err)
+	}
+}
+
+func Test_verifyLegacySignature(t *testing.T) {
+	// testnet3 addresses are also valid on signet and regtest
+	for _, params := range []*chaincfg.Params{&chaincfg.SigNetParams, &chaincfg.RegressionNetParams} {
+		if err := verifyLegacySignature("mk7YZqsP6jEJ4XNqdDQEXYwR4umRKceddR", "Test", "IIQCJwCvFQ62E7JlOsozLbyjybqLE719G1hPxZcJBANGIxP7rtv5Rg9RFJ3gsBe19kbeFyKfaKqFGUIGbuZHORI=", params); err != nil {
+			t.Errorf("verifyLegacySignature() %s = %v", params.Name, err)
+		}
+	}
+	if err := verifyLegacySignature("mk7YZqsP6jEJ4XNqdDQEXYwR4umRKceddR", "Test", "IIQCJwCvFQ62E7JlOsozLbyjybqLE719G1hPxZcJBANGIxP7rtv5Rg9RFJ3gsBe19kbeFyKfaKqFGUIGbuZHORI=", &chaincfg.MainNetParams); !errors.Is(err, ErrMalformedKey) {
+		t.Errorf("verifyLegacySignature() mainnet = %v", err)
+	}
+}
+
+func Test_verifyKeySignatureErrors(t *testing.T) {
+	ecdsaKey := "024d1d2028d6a503c5d688425eddcb9a348696d606fb6d521b8a336de760d51e8e"
+	ecdsaSignature := "30440220046e86f0bff9639a893616e1db3abfa24cafa8818e7e47798c860d5982968ef502200241904a24128f6f73b8f5675368ff85992aa2b97bb40fe91ab361c96c62ca35"
+	naclKey := strings.Repeat("00", 32)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		publicKey string
+		signature string
+		want      error
+	}{
+		{"unknown", "rsa", ecdsaKey, ecdsaSignature, ErrUnknownAlgorithm},
+		{"nacl-short-key", AlgorithmNacl, "00", strings.Repeat("00", 64), ErrMalformedKey},
+		{"nacl-hex-key", AlgorithmNacl, "zz", strings.Repeat("00", 64), ErrMalformedKey},
+		{"nacl-short-signature", AlgorithmNacl, naclKey, "00", ErrMalformedSignature},
+		{"nacl-bad", AlgorithmNacl, naclKey, strings.Repeat("00", 64), ErrBadSignature},
+		{"ecdsa-key", AlgorithmEcdsa, "0200", ecdsaSignature, ErrMalformedKey},
+		{"ecdsa-hex-signature", AlgorithmEcdsa, ecdsaKey, "zz", ErrMalformedSignature},
+		{"ecdsa-der-signature", AlgorithmEcdsa, ecdsaKey, "3000", ErrMalformedSignature},
+		{"ecdsa-bad", AlgorithmEcdsa, ecdsaKey, ecdsaSignature, ErrBadSignature},
+		{"schnorr-key", AlgorithmSchnorr, "00", strings.Repeat("00", 64), ErrMalformedKey},
+		{"testnet3-address", AlgorithmTestnet3, "invalid", "AA==", ErrMalformedKey},
+		{"testnet3-signature", AlgorithmTestnet3, "mk7YZqsP6jEJ4XNqdDQEXYwR4umRKceddR", "AA==", ErrMalformedSignature},
+		{"bip322-signature", AlgorithmBip322, "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", "!", ErrMalformedSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := ConfidentialKey{Algorithm: tt.algorithm, PublicKey: tt.publicKey}
+			err := verifyKeySignature(key, tt.publicKey, "message", tt.algorithm, tt.signature)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("verifyKeySignature() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	key := ConfidentialKey{Algorithm: AlgorithmNacl, PublicKey: naclKey}
+	if err := verifyKeySignature(key, naclKey, "message", "", ""); !errors.Is(err, ErrAlgorithmMismatch) {
+		t.Errorf("verifyKeySignature() nacl without algorithm = %v", err)
+	}
+	key = ConfidentialKey{Algorithm: AlgorithmEcdsa, PublicKey: ecdsaKey}
+	if err := verifyKeySignature(key, ecdsaKey, "message", AlgorithmNacl, ecdsaSignature); !errors.Is(err, ErrAlgorithmMismatch) {
+		t.Errorf("verifyKeySignature() mismatch = %v", err)
+	}
+}
@@ -17,24 +17,47 @@ const (
 
 type torClientsInfo struct {
 	sync.Mutex
-	clients []*tor.Tor
+	clients  []*tor.Tor
+	external *tor.Tor
 }
 
 func WithTorContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, TorClientsKeys, &torClientsInfo{})
 }
 
-func AddTorClient(ctx context.Context, torClient *tor.Tor) {
+func torClientsFromContext(ctx context.Context) *torClientsInfo {
 	torContext := ctx.Value(TorClientsKeys)
 	if torContext == nil {
 		panic("Create context with Tor")
 	}
-	if torClients, ok := torContext.(*torClientsInfo); ok {
-		torClients.Lock()
-		defer torClients.Unlock()
+	torClients, ok := torContext.(*torClientsInfo)
+	if !ok {
+		panic("Invalid Tor context")
+	}
+	return torClients
+}
 
-		torClients.clients = append(torClients.clients, torClient)
-		log.Debug("Tor client added")
+func AddTorClient(ctx context.Context, torClient *tor.Tor) {
+	torClients := torClientsFromContext(ctx)
+	torClients.Lock()
+	defer torClients.Unlock()
+
+	torClients.clients = append(torClients.clients, torClient)
+	log.Debug("Tor client added")
+}
+
+// RemoveTorClient remove a closed tor client, it is not closed again on shutdown
+func RemoveTorClient(ctx context.Context, torClient *tor.Tor) {
+	torClients := torClientsFromContext(ctx)
+	torClients.Lock()
+	defer torClients.Unlock()
+
+	for i, client := range torClients.clients {
+		if client == torClient {
+			torClients.clients = append(torClients.clients[:i], torClients.clients[i+1:]...)
+			log.Debug("Tor client removed")
+			return
+		}
 	}
 }
 
@@ -57,5 +80,6 @@ func Shutdown(ctx context.Context) {
 			}
 		}
 		torClients.clients = torClients.clients[:0]
+		torClients.external = nil
 	}
 }
@@ -0,0 +1,22 @@
+package soroban
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cretz/bine/tor"
+)
+
+func Test_RemoveTorClient(t *testing.T) {
+	ctx := WithTorContext(context.Background())
+	first, restarted := &tor.Tor{}, &tor.Tor{}
+	AddTorClient(ctx, first)
+	AddTorClient(ctx, restarted)
+
+	RemoveTorClient(ctx, first)
+	RemoveTorClient(ctx, first)
+	clients := torClientsFromContext(ctx).clients
+	if len(clients) != 1 || clients[0] != restarted {
+		t.Errorf("RemoveTorClient() clients = %v", clients)
+	}
+}
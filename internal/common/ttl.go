@@ -1,30 +1,40 @@
 package common
 
 import (
+	"sync"
 	"time"
 )
 
-// TimeToLive return duration from mode.
-func TimeToLive(mode string) time.Duration {
-	if len(mode) == 0 {
-		mode = "default"
+var (
+	ttlMtx   sync.RWMutex
+	ttlModes = map[string]time.Duration{
+		"fast":   15 * time.Second,
+		"short":  time.Minute,
+		"normal": 3 * time.Minute,
+		"long":   5 * time.Minute,
 	}
+)
 
-	switch mode {
-	case "fast":
-		return 15 * time.Second
+// SetTimeToLive replace modes durations, missing modes are kept
+func SetTimeToLive(modes map[string]time.Duration) {
+	ttlMtx.Lock()
+	defer ttlMtx.Unlock()
 
-	case "short":
-		return time.Minute
+	for mode, ttl := range modes {
+		if _, ok := ttlModes[mode]; ok && ttl > 0 {
+			ttlModes[mode] = ttl
+		}
+	}
+}
 
-	case "long":
-		return 5 * time.Minute
+// TimeToLive return duration from mode.
+func TimeToLive(mode string) time.Duration {
+	ttlMtx.RLock()
+	defer ttlMtx.RUnlock()
 
-	case "normal":
-		fallthrough
-	case "default":
-		fallthrough
-	default:
-		return 3 * time.Minute
+	if ttl, ok := ttlModes[mode]; ok {
+		return ttl
 	}
+	// default and unknown modes
+	return ttlModes["normal"]
 }
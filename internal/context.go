@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"crypto/ed25519"
 
 	soroban "code.samourai.io/wallet/samourai-soroban"
 	"code.samourai.io/wallet/samourai-soroban/ipc"
@@ -14,6 +15,7 @@ const (
 	SorobanDirectoryKey = ContextKey("soroban-directory")
 	SorobanP2PKey       = ContextKey("soroban-p2p")
 	SorobanIPCKey       = ContextKey("soroban-ipc")
+	SorobanSignerKey    = ContextKey("soroban-signer")
 )
 
 func DirectoryFromContext(ctx context.Context) soroban.Directory {
@@ -30,3 +32,9 @@ func IPCFromContext(ctx context.Context) *ipc.IPCService {
 	result, _ := ctx.Value(SorobanIPCKey).(*ipc.IPCService)
 	return result
 }
+
+// SignerFromContext return node signing key, nil if responses are not signed
+func SignerFromContext(ctx context.Context) ed25519.PrivateKey {
+	result, _ := ctx.Value(SorobanSignerKey).(ed25519.PrivateKey)
+	return result
+}
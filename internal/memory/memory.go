@@ -184,3 +184,93 @@ func remove(slice []*valueEntry, s int) []*valueEntry {
 func now() time.Time {
 	return time.Now().Truncate(time.Millisecond).UTC()
 }
+
+// Snapshot return all non-expired keys and values.
+func (m *Memory) Snapshot() ([]soroban.DirectorySnapshotEntry, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	now := now()
+	var result []soroban.DirectorySnapshotEntry
+	for _, key := range m.cache.Keys() {
+		name, ok := key.(string)
+		if !ok {
+			continue
+		}
+		entry, ok := m.cache.Peek(name)
+		if !ok {
+			continue
+		}
+		list, ok := entry.(*keyList)
+		if !ok {
+			continue
+		}
+
+		var values []soroban.DirectorySnapshotValue
+		for _, value := range list.values {
+			if value.expireOn.Before(now) {
+				continue
+			}
+			values = append(values, soroban.DirectorySnapshotValue{
+				Value:    value.value,
+				ExpireOn: value.expireOn,
+			})
+		}
+		if len(values) == 0 {
+			continue
+		}
+		result = append(result, soroban.DirectorySnapshotEntry{
+			Key:    name,
+			TTL:    list.TTL,
+			Values: values,
+		})
+	}
+	return result, nil
+}
+
+// Restore add entries to directory, expired values are ignored.
+func (m *Memory) Restore(entries []soroban.DirectorySnapshotEntry) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	now := now()
+	for _, entry := range entries {
+		if len(entry.Key) == 0 {
+			return common.InvalidArgsErr
+		}
+
+		list := getKeyList(m.cache, entry.Key)
+		if entry.TTL > list.TTL {
+			list.TTL = entry.TTL
+		}
+		for _, value := range entry.Values {
+			if value.ExpireOn.Before(now) {
+				continue
+			}
+			exists, pos := contains(list.values, value.Value)
+			if !exists {
+				list.values = append(list.values, &valueEntry{
+					value:    value.Value,
+					expireOn: value.ExpireOn,
+				})
+			} else if list.values[pos].expireOn.Before(value.ExpireOn) {
+				list.values[pos].expireOn = value.ExpireOn
+			}
+		}
+
+		purgeKeyList(list, now)
+		if len(list.values) == 0 {
+			continue
+		}
+
+		// keep key until the last value expires
+		ttl := list.TTL
+		for _, value := range list.values {
+			if remaining := value.expireOn.Sub(now); remaining > ttl {
+				ttl = remaining
+			}
+		}
+		m.cache.StoreWithTTL(entry.Key, list, ttl)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/internal/common"
+)
+
+func Test_MemorySnapshotRestore(t *testing.T) {
+	source := NewWithDomain("test", DefaultCacheCapacity, DefaultCacheTTL)
+	source.Add("key", "value1", time.Minute)
+	source.Add("key", "value2", time.Hour)
+
+	entries, err := source.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].TTL != time.Hour || len(entries[0].Values) != 2 {
+		t.Fatalf("Snapshot() = %v", entries)
+	}
+
+	target := NewWithDomain("test", DefaultCacheCapacity, DefaultCacheTTL)
+	if err := target.Restore(entries); err != nil {
+		t.Fatal(err)
+	}
+	values, _ := target.List("key")
+	sort.Strings(values)
+	if len(values) != 2 || values[0] != "value1" || values[1] != "value2" {
+		t.Errorf("List() restored = %v", values)
+	}
+}
+
+func Test_MemoryRestoreExpired(t *testing.T) {
+	m := NewWithDomain("test", DefaultCacheCapacity, DefaultCacheTTL)
+	now := time.Now().UTC()
+	err := m.Restore([]soroban.DirectorySnapshotEntry{
+		{
+			Key: common.KeyHash("test", "partial"),
+			TTL: time.Minute,
+			Values: []soroban.DirectorySnapshotValue{
+				{Value: "expired", ExpireOn: now.Add(-time.Second)},
+				{Value: "valid", ExpireOn: now.Add(time.Minute)},
+			},
+		},
+		{
+			Key: common.KeyHash("test", "expired"),
+			TTL: time.Minute,
+			Values: []soroban.DirectorySnapshotValue{
+				{Value: "expired", ExpireOn: now.Add(-time.Second)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values, _ := m.List("partial"); len(values) != 1 || values[0] != "valid" {
+		t.Errorf("List() partial = %v", values)
+	}
+	if values, _ := m.List("expired"); len(values) != 0 {
+		t.Errorf("List() expired = %v", values)
+	}
+	entries, _ := m.Snapshot()
+	if len(entries) != 1 {
+		t.Errorf("Snapshot() = %v", entries)
+	}
+}
+
+func Test_MemoryRestoreMerge(t *testing.T) {
+	m := NewWithDomain("test", DefaultCacheCapacity, DefaultCacheTTL)
+	m.Add("key", "current", time.Minute)
+	m.Add("key", "shared", time.Minute)
+
+	now := time.Now().UTC()
+	err := m.Restore([]soroban.DirectorySnapshotEntry{
+		{
+			Key: common.KeyHash("test", "key"),
+			TTL: time.Hour,
+			Values: []soroban.DirectorySnapshotValue{
+				{Value: "shared", ExpireOn: now.Add(30 * time.Minute)},
+				{Value: "current", ExpireOn: now.Add(time.Second)},
+				{Value: "restored", ExpireOn: now.Add(time.Hour)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := m.Snapshot()
+	if len(entries) != 1 || entries[0].TTL != time.Hour || len(entries[0].Values) != 3 {
+		t.Fatalf("Snapshot() = %v", entries)
+	}
+	for _, value := range entries[0].Values {
+		remaining := value.ExpireOn.Sub(now)
+		switch value.Value {
+		case "current":
+			// earlier expiration does not shorten existing value
+			if remaining < 50*time.Second {
+				t.Errorf("current expires in %s", remaining)
+			}
+		case "shared":
+			if remaining < 29*time.Minute {
+				t.Errorf("shared expires in %s", remaining)
+			}
+		case "restored":
+			if remaining < 59*time.Minute {
+				t.Errorf("restored expires in %s", remaining)
+			}
+		}
+	}
+}
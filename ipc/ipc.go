@@ -95,7 +95,29 @@ func (p *IPCService) Request(request Message, direction string) (Message, error)
 	return resp, nil
 }
 
+// Broadcast send message to all child processes, without response
+func (p *IPCService) Broadcast(request Message) error {
+	data, err := json.Marshal(&request)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s.%s", p.options.Subject, "broadcast")
+	log.WithField("subject", subject).Debug("IPC Broadcast")
+	return p.conn.Publish(subject, data)
+}
+
 func (p *IPCService) ListenFromServer(ctx context.Context, ipcSubject string, ipcHandler MessageHandler) {
+	broadcast, err := p.conn.Subscribe(fmt.Sprintf("%s.%s", ipcSubject, "broadcast"), func(msg *nats.Msg) {
+		handleNatsMessage(ctx, msg, ipcHandler)
+	})
+	if err != nil {
+		log.WithError(err).
+			Fatal("Failed to subscribe")
+		return
+	}
+	defer broadcast.Unsubscribe()
+
 	subject := fmt.Sprintf("%s.%s", ipcSubject, "down")
 	log.WithField("subject", subject).Info("Child register for server requests")
 	for i := 0; i < 16; i++ {
@@ -3,6 +3,7 @@ package ipc
 import (
 	"bufio"
 	"context"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -10,7 +11,22 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// InputFd is the file descriptor of input in sub process
+const InputFd = 3
+
 func StartProcessDaemon(ctx context.Context, name, process string, args ...string) {
+	StartProcessDaemonWithInput(ctx, name, process, nil, args...)
+}
+
+// StartProcessDaemonWithInput restart sub process when it exits.
+// Input is written to a pipe available as InputFd in sub process.
+func StartProcessDaemonWithInput(ctx context.Context, name, process string, input []byte, args ...string) {
+	StartProcessDaemonWithEnv(ctx, name, process, nil, input, args...)
+}
+
+// StartProcessDaemonWithEnv restart sub process with environment env when it exits.
+// Nil env inherits current process environment.
+func StartProcessDaemonWithEnv(ctx context.Context, name, process string, env []string, input []byte, args ...string) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -18,13 +34,14 @@ func StartProcessDaemon(ctx context.Context, name, process string, args ...strin
 			log.Trace("Starting process")
 
 			<-time.After(3 * time.Second)
-			startSubProcess(ctx, name, process, args...)
+			startSubProcess(ctx, name, process, env, input, args...)
 		}
 	}
 }
 
-func startSubProcess(ctx context.Context, name, process string, args ...string) {
+func startSubProcess(ctx context.Context, name, process string, env []string, input []byte, args ...string) {
 	cmd := exec.Command(process, args...)
+	cmd.Env = env
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		log.WithError(err).Fatal("Failed to get process stdout")
@@ -32,10 +49,29 @@ func startSubProcess(ctx context.Context, name, process string, args ...string)
 	cmd.Stderr = cmd.Stdout
 	defer stdout.Close()
 
+	var inputWriter *os.File
+	if input != nil {
+		var inputReader *os.File
+		inputReader, inputWriter, err = os.Pipe()
+		if err != nil {
+			log.WithError(err).Fatal("Failed to create process input")
+		}
+		cmd.ExtraFiles = []*os.File{inputReader} // InputFd
+		defer inputReader.Close()
+	}
+
 	if err := cmd.Start(); err != nil {
 		log.WithError(err).Fatal("Failed to start sub process")
 	}
 
+	if inputWriter != nil {
+		_, err = inputWriter.Write(input)
+		inputWriter.Close()
+		if err != nil {
+			log.WithError(err).Error("Failed to write process input")
+		}
+	}
+
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
 		text := strings.Trim(scanner.Text(), "\n\r ")
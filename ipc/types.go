@@ -21,4 +21,5 @@ const (
 	MessageTypeSoroban MessageType = "soroban"
 	MessageTypeP2P     MessageType = "p2p"
 	MessageTypeIPC     MessageType = "ipc"
+	MessageTypeReload  MessageType = "reload"
 )
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package soroban
+
+import (
+	"os"
+)
+
+// lockFile is not supported, tor also lock its data directory
+func lockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package soroban
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile take an exclusive non-blocking lock on file
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
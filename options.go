@@ -1,8 +1,17 @@
 package soroban
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
@@ -13,6 +22,7 @@ var (
 		Soroban: SorobanInfo{
 			Config:        "",
 			Confidential:  "",
+			ClientAuth:    "",
 			Domain:        "samourai",
 			DirectoryType: "default",
 			WithTor:       false,
@@ -21,16 +31,38 @@ var (
 			Port:          4242,
 			Announce:      "soroban.announce.nodes",
 			IPv4:          false,
+			AdminPort:     0,
+			TLSCertFile:   "",
+			TLSKeyFile:    "",
+			TLSSelfSigned: false,
+			UnixSocket:    "",
+			UnixMode:      "0660",
+
+			AnnounceInterval: 15 * time.Second,
+			AnnounceMode:     "short",
+			AnnounceUnsigned: true,
+
+			SignatureWindow: 5 * time.Minute,
+			ReplayCacheSize: 100000,
 		},
 		P2P: P2PInfo{
-			Seed:          "",
-			Bootstrap:     "",
-			ListenPort:    1042,
-			LowWater:      16, // = 2*Gossip.Dlo
-			HighWater:     40, // = 2*Gossip.Dhi
-			Room:          "samourai-p2p",
-			DHTServerMode: false,
-			PeerstoreFile: "-",
+			Seed:           "",
+			IdentityFile:   "",
+			Bootstrap:      "",
+			Transport:      "tor",
+			Hostname:       "0.0.0.0",
+			ListenPort:     1042,
+			LowWater:       16, // = 2*Gossip.Dlo
+			HighWater:      40, // = 2*Gossip.Dhi
+			Room:           "samourai-p2p",
+			DHTServerMode:  false,
+			PeerstoreFile:  "-",
+			MDNS:           false,
+			MessageFormat:  "auto",
+			Compression:    "zstd",
+			Shards:         0,
+			ShardSubscribe: "",
+			ShardMiss:      "forward",
 		},
 		Gossip: GossipInfo{
 			D:          10, // = ceil(exp(ln(NB_P2P_NODES)/AVG_NB_HOPS))
@@ -41,6 +73,26 @@ var (
 			Dlazy:      10, // = Gossip.D
 			PrunePeers: 40, // = 2*Gossip.Dhi
 			Limit:      40, // = 2*Gossip.Dhi
+			PeerScore:  false,
+		},
+		Tor: TorInfo{
+			ControlAddress:  "",
+			ControlPassword: "",
+			SocksAddress:    "",
+			DNSAddress:      "",
+			DataDir:         "",
+		},
+		TTL: TTLInfo{
+			Fast:   15 * time.Second,
+			Short:  time.Minute,
+			Normal: 3 * time.Minute,
+			Long:   5 * time.Minute,
+		},
+		Heartbeat: HeartbeatInfo{
+			Interval:       30 * time.Second,
+			StartupTimeout: 15 * time.Minute,
+			Timeout:        3 * time.Minute,
+			Recovery:       "exit",
 		},
 		IPC: IPCInfo{
 			Subject:           "ipc.server",
@@ -53,127 +105,336 @@ var (
 )
 
 type Options struct {
-	LogLevel string
-	LogFile  string
-	Soroban  SorobanInfo
-	P2P      P2PInfo
-	IPC      IPCInfo
-	Gossip   GossipInfo
+	LogLevel  string
+	LogFile   string
+	Version   string `yaml:"-"`
+	Soroban   SorobanInfo
+	P2P       P2PInfo
+	IPC       IPCInfo
+	Gossip    GossipInfo
+	Tor       TorInfo
+	TTL       TTLInfo
+	Heartbeat HeartbeatInfo
+	Listeners []ListenerInfo
 }
 
-func (p *Options) Load(config string) {
+// LoadFile apply configuration file over current options, read, parse and unknown field errors are returned
+func (p *Options) LoadFile(config string) error {
 	if len(config) == 0 {
-		return
+		return nil
 	}
-	if data, err := os.ReadFile(config); err == nil {
-		var o Options
-		if err := o.parse(data); err == nil {
-			p.Merge(o)
-		}
+	data, err := os.ReadFile(config)
+	if err != nil {
+		return err
 	}
+	return p.parse(data)
 }
 
 func (p *Options) parse(data []byte) error {
-	return yaml.Unmarshal(data, p)
+	return yaml.UnmarshalStrict(data, p)
 }
 
-func (p *Options) Merge(o Options) {
-	if len(o.LogLevel) > 0 {
-		p.LogLevel = o.LogLevel
+// Validate check options consistency, all errors are returned
+func (p *Options) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
 
-	if len(o.LogFile) > 0 {
-		p.LogFile = o.LogFile
+	_, err := log.ParseLevel(p.LogLevel)
+	check(err == nil, "log: invalid level %q", p.LogLevel)
+
+	check(validPort(p.Soroban.Port), "soroban.port: %d out of range", p.Soroban.Port)
+	check(p.Soroban.AdminPort == 0 || validPort(p.Soroban.AdminPort), "soroban.adminport: %d out of range", p.Soroban.AdminPort)
+	check(validSeed(p.Soroban.Seed), "soroban.seed: must be %d hex characters", SeedHexLength)
+	check(len(p.Soroban.Seed) == 0 || p.Soroban.WithTor, "soroban.seed: can't use seed without tor (soroban.withtor)")
+	check(p.Soroban.AnnounceInterval >= time.Second, "soroban.announceinterval: %s must be at least 1s", p.Soroban.AnnounceInterval)
+	_, ok := p.TTL.Modes()[p.Soroban.AnnounceMode]
+	check(ok, "soroban.announcemode: invalid mode %q (fast, short, normal, long)", p.Soroban.AnnounceMode)
+	for _, key := range p.Soroban.AnnounceKeyList() {
+		_, err := hex.DecodeString(key)
+		check(err == nil && len(key) == SeedHexLength, "soroban.announcekeys: %q must be %d hex characters", key, SeedHexLength)
+	}
+	check(p.Soroban.SignatureWindow >= time.Second && p.Soroban.SignatureWindow <= 24*time.Hour, "soroban.signaturewindow: %s must be between 1s and 24h", p.Soroban.SignatureWindow)
+	check(p.Soroban.SigningSeed == SigningSeedOnion || validSeed(p.Soroban.SigningSeed), "soroban.signingseed: must be %d hex characters or %s", SeedHexLength, SigningSeedOnion)
+	check(p.Soroban.SigningSeed != SigningSeedOnion || len(p.Soroban.Seed) > 0, "soroban.signingseed: %s requires soroban.seed", SigningSeedOnion)
+	check(p.Soroban.ReplayCacheSize >= 0, "soroban.replaycachesize: %d must be positive", p.Soroban.ReplayCacheSize)
+
+	check(p.P2P.Seed == "auto" || validSeed(p.P2P.Seed), "p2p.seed: must be %d hex characters or auto", SeedHexLength)
+	check(validPort(p.P2P.ListenPort) && validPort(p.P2P.ListenPort+p.IPC.ChildProcessCount), "p2p.listenport: %d out of range", p.P2P.ListenPort)
+	check(p.P2P.LowWater <= p.P2P.HighWater, "p2p: lowwater (%d) must be lower than highwater (%d)", p.P2P.LowWater, p.P2P.HighWater)
+	check(oneOf(p.P2P.Transport, "tor", "tcp", "quic", "tcp+tor"), "p2p.transport: invalid transport %q (tor, tcp, quic, tcp+tor)", p.P2P.Transport)
+	check(oneOf(p.P2P.MessageFormat, "auto", "legacy", "envelope"), "p2p.messageformat: invalid format %q (auto, legacy, envelope)", p.P2P.MessageFormat)
+	check(oneOf(p.P2P.Compression, "zstd", "none"), "p2p.compression: invalid compression %q (zstd, none)", p.P2P.Compression)
+	check(p.P2P.Shards >= 0, "p2p.shards: %d must be positive", p.P2P.Shards)
+	for _, tok := range strings.Split(p.P2P.ShardSubscribe, ",") {
+		tok = strings.TrimSpace(tok)
+		if len(tok) == 0 {
+			continue
+		}
+		index, err := strconv.Atoi(tok)
+		check(err == nil && index >= 0 && index < p.P2P.Shards, "p2p.shardsubscribe: invalid shard index %q (0 to p2p.shards-1)", tok)
 	}
+	check(oneOf(p.P2P.ShardMiss, "forward", "local"), "p2p.shardmiss: invalid policy %q (forward, local)", p.P2P.ShardMiss)
 
-	p.Soroban.Merge(o.Soroban)
-	p.P2P.Merge(o.P2P)
-	p.Gossip.Merge(o.Gossip)
-	p.IPC.Merge(o.IPC)
-}
+	check(p.Gossip.Dlo <= p.Gossip.D && p.Gossip.D <= p.Gossip.Dhi, "gossip: must be dlo (%d) <= d (%d) <= dhi (%d)", p.Gossip.Dlo, p.Gossip.D, p.Gossip.Dhi)
+	check(p.Gossip.Dout < p.Gossip.Dlo && p.Gossip.Dout <= p.Gossip.D/2, "gossip: dout (%d) must be lower than dlo (%d) and d/2 (%d)", p.Gossip.Dout, p.Gossip.Dlo, p.Gossip.D/2)
 
-type SorobanInfo struct {
-	Config        string
-	Confidential  string
-	Domain        string
-	DirectoryType string
-	WithTor       bool
-	Seed          string
-	Hostname      string
-	Port          int
-	Announce      string
-	IPv4          bool
-}
+	check(validPort(p.IPC.NatsPort), "ipc.natsport: %d out of range", p.IPC.NatsPort)
 
-func (p *SorobanInfo) Merge(s SorobanInfo) {
-	if len(s.Config) > 0 {
-		p.Config = s.Config
-	}
-	if len(s.Confidential) > 0 {
-		p.Confidential = s.Confidential
-	}
-	if len(s.Domain) > 0 {
-		p.Domain = s.Domain
-	}
-	if len(s.DirectoryType) > 0 {
-		p.DirectoryType = s.DirectoryType
-	}
-	if s.WithTor {
-		p.WithTor = s.WithTor
-	}
-	if len(s.Seed) > 0 {
-		p.Seed = s.Seed
-	}
-	if len(s.Hostname) > 0 {
-		p.Hostname = s.Hostname
+	for _, action := range strings.Split(p.Heartbeat.Recovery, ",") {
+		action = strings.TrimSpace(action)
+		check(validRecoveryAction(action), "heartbeat.recovery: invalid action %q (exit, rebootstrap, reconnect, restart)", action)
 	}
-	if s.Port > 0 {
-		p.Port = s.Port
+
+	modes := p.TTL.Modes()
+	for _, mode := range []string{"fast", "short", "normal", "long"} {
+		check(modes[mode] >= time.Second, "ttl.%s: %s must be at least 1s", mode, modes[mode])
 	}
-	if len(s.Announce) > 0 {
-		p.Announce = s.Announce
+
+	for i, listener := range p.Listeners {
+		name := listener.Name
+		if len(name) == 0 {
+			name = fmt.Sprintf("%d", i)
+		}
+		switch listener.Type {
+		case ListenerOnion:
+			check(validSeed(listener.Seed), "listeners.%s.seed: must be %d hex characters", name, SeedHexLength)
+			check(listener.Port == 0 || validPort(listener.Port), "listeners.%s.port: %d out of range", name, listener.Port)
+		case ListenerTCP:
+			check(validPort(listener.Port), "listeners.%s.port: %d out of range", name, listener.Port)
+		case ListenerUnix:
+			check(len(listener.Path) > 0, "listeners.%s.path: required for unix listener", name)
+		default:
+			check(false, "listeners.%s.type: invalid type %q (onion, tcp, unix)", name, listener.Type)
+		}
 	}
-	if s.IPv4 {
-		p.IPv4 = s.IPv4
+
+	return errors.Join(errs...)
+}
+
+// reloadableOptions are applied at runtime on reload
+var reloadableOptions = map[string]bool{
+	"loglevel":                 true,
+	"soroban.announce":         true,
+	"soroban.announceinterval": true,
+	"soroban.announcemode":     true,
+	"soroban.announceunsigned": true,
+	"soroban.announcekeys":     true,
+	"soroban.signaturewindow":  true,
+	"soroban.replaycachesize":  true,
+	"ttl":                      true,
+	"gossip":                   true,
+}
+
+// RestartRequired return changed options which are not applied on reload
+func (p *Options) RestartRequired(o Options) []string {
+	var result []string
+	diffOptions("", reflect.ValueOf(*p), reflect.ValueOf(o), &result)
+	return result
+}
+
+// ReloadableOptions are sent to child processes on reload, seeds and passwords are never included
+type ReloadableOptions struct {
+	LogLevel         string
+	Announce         string
+	AnnounceInterval time.Duration
+	AnnounceMode     string
+	AnnounceUnsigned bool
+	AnnounceKeys     string
+	SignatureWindow  time.Duration
+	ReplayCacheSize  int
+	TTL              TTLInfo
+	Gossip           GossipInfo
+}
+
+// Reloadable return reloadable options
+func (p Options) Reloadable() ReloadableOptions {
+	return ReloadableOptions{
+		LogLevel:         p.LogLevel,
+		Announce:         p.Soroban.Announce,
+		AnnounceInterval: p.Soroban.AnnounceInterval,
+		AnnounceMode:     p.Soroban.AnnounceMode,
+		AnnounceUnsigned: p.Soroban.AnnounceUnsigned,
+		AnnounceKeys:     p.Soroban.AnnounceKeys,
+		SignatureWindow:  p.Soroban.SignatureWindow,
+		ReplayCacheSize:  p.Soroban.ReplayCacheSize,
+		TTL:              p.TTL,
+		Gossip:           p.Gossip,
 	}
 }
 
-type P2PInfo struct {
-	Seed          string
-	Bootstrap     string
-	ListenPort    int
-	LowWater      int
-	HighWater     int
-	Room          string
-	DHTServerMode bool
-	PeerstoreFile string
+// WithReloadable return options with reloadable options from o
+func (p Options) WithReloadable(o Options) Options {
+	return p.WithReloadableOptions(o.Reloadable())
 }
 
-func (p *P2PInfo) Merge(i P2PInfo) {
-	if len(i.Seed) > 0 {
-		p.Seed = i.Seed
+// WithReloadableOptions return options with reloadable options r
+func (p Options) WithReloadableOptions(r ReloadableOptions) Options {
+	p.LogLevel = r.LogLevel
+	p.Soroban.Announce = r.Announce
+	p.Soroban.AnnounceInterval = r.AnnounceInterval
+	p.Soroban.AnnounceMode = r.AnnounceMode
+	p.Soroban.AnnounceUnsigned = r.AnnounceUnsigned
+	p.Soroban.AnnounceKeys = r.AnnounceKeys
+	p.Soroban.SignatureWindow = r.SignatureWindow
+	p.Soroban.ReplayCacheSize = r.ReplayCacheSize
+	p.TTL = r.TTL
+	p.Gossip = r.Gossip
+	return p
+}
+
+func diffOptions(name string, a, b reflect.Value, result *[]string) {
+	if reloadableOptions[name] {
+		return
 	}
-	if len(i.Bootstrap) > 0 {
-		p.Bootstrap = i.Bootstrap
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*result = append(*result, name)
+		}
+		return
 	}
-	if i.ListenPort > 0 {
-		p.ListenPort = i.ListenPort
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if field.Tag.Get("yaml") == "-" {
+			continue
+		}
+		fieldName := strings.ToLower(field.Name)
+		if len(name) > 0 {
+			fieldName = name + "." + fieldName
+		}
+		diffOptions(fieldName, a.Field(i), b.Field(i), result)
 	}
-	if i.LowWater > 0 {
-		p.LowWater = i.LowWater
+}
+
+// SeedHexLength is the length of hex encoded ed25519 seeds
+const SeedHexLength = 2 * 32
+
+// SigningSeedOnion use the onion seed as node signing key
+const SigningSeedOnion = "onion"
+
+// NodeSigningSeed return the hex seed of node signing key, empty if responses are not signed
+func (p *SorobanInfo) NodeSigningSeed() string {
+	if p.SigningSeed == SigningSeedOnion {
+		return p.Seed
 	}
-	if i.HighWater > 0 {
-		p.HighWater = i.HighWater
+	return p.SigningSeed
+}
+
+// AnnounceKeyList return keys allowed to sign announces of non-onion urls
+func (p *SorobanInfo) AnnounceKeyList() []string {
+	var result []string
+	for _, key := range strings.Split(p.AnnounceKeys, ",") {
+		key = strings.TrimSpace(key)
+		if len(key) > 0 {
+			result = append(result, strings.ToLower(key))
+		}
 	}
-	if len(i.Room) > 0 {
-		p.Room = i.Room
+	return result
+}
+
+func validSeed(seed string) bool {
+	if len(seed) == 0 {
+		return true
 	}
-	if i.DHTServerMode {
-		p.DHTServerMode = i.DHTServerMode
+	_, err := hex.DecodeString(seed)
+	return err == nil && len(seed) == SeedHexLength
+}
+
+func oneOf(value string, values ...string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
 	}
-	if len(i.PeerstoreFile) > 0 {
-		p.PeerstoreFile = i.PeerstoreFile
+	return false
+}
+
+var (
+	recoveryActionsMtx sync.Mutex
+	recoveryActions    = map[string]bool{
+		"exit":        true,
+		"rebootstrap": true,
+		"reconnect":   true,
+		"restart":     true,
 	}
+)
+
+// AddRecoveryAction allow a custom heartbeat recovery action, used by p2p.RegisterRecovery
+func AddRecoveryAction(name string) {
+	recoveryActionsMtx.Lock()
+	defer recoveryActionsMtx.Unlock()
+
+	recoveryActions[name] = true
+}
+
+func validRecoveryAction(name string) bool {
+	recoveryActionsMtx.Lock()
+	defer recoveryActionsMtx.Unlock()
+
+	return recoveryActions[name]
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+type SorobanInfo struct {
+	Config        string
+	Confidential  string
+	ClientAuth    string
+	Domain        string
+	DirectoryType string
+	WithTor       bool
+	Seed          string
+	Hostname      string
+	Port          int
+	Announce      string
+	IPv4          bool
+	AdminPort     int
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSSelfSigned bool
+	UnixSocket    string
+	UnixMode      string
+
+	// AnnounceInterval is the delay between node announces
+	AnnounceInterval time.Duration
+	// AnnounceMode is the announce entries time to live mode
+	AnnounceMode string
+	// AnnounceUnsigned accept announces without signature, or not bound to their url
+	AnnounceUnsigned bool
+	// AnnounceKeys are the hex ed25519 keys, comma separated, allowed to sign announces of non-onion urls
+	AnnounceKeys string
+
+	// SignatureWindow is the maximum delta between signed requests timestamp and server time
+	SignatureWindow time.Duration
+	// ReplayCacheSize is the number of signed requests remembered to reject replays, 0 to disable
+	ReplayCacheSize int
+	// SigningSeed is the node ed25519 key seed used to sign responses, "onion" use Seed, empty to disable
+	SigningSeed string
+}
+
+type P2PInfo struct {
+	Seed           string
+	IdentityFile   string
+	Bootstrap      string
+	Transport      string
+	Hostname       string
+	ListenPort     int
+	LowWater       int
+	HighWater      int
+	Room           string
+	DHTServerMode  bool
+	PeerstoreFile  string
+	MDNS           bool
+	MessageFormat  string
+	Compression    string
+	Shards         int
+	ShardSubscribe string
+	ShardMiss      string
 }
 
 type GossipInfo struct {
@@ -185,57 +446,86 @@ type GossipInfo struct {
 	Dlazy      int
 	PrunePeers int
 	Limit      int
+	PeerScore  bool
 }
 
-func (p *GossipInfo) Merge(i GossipInfo) {
-	if i.D > 0 {
-		p.D = i.D
-	}
-	if i.Dlo > 0 {
-		p.Dlo = i.Dlo
-	}
-	if i.Dhi > 0 {
-		p.Dhi = i.Dhi
-	}
-	if i.Dout > 0 {
-		p.Dout = i.Dout
-	}
-	if i.Dscore > 0 {
-		p.Dscore = i.Dscore
-	}
-	if i.Dlazy > 0 {
-		p.Dlazy = i.Dlazy
-	}
-	if i.PrunePeers > 0 {
-		p.PrunePeers = i.PrunePeers
+const (
+	ListenerOnion = "onion"
+	ListenerTCP   = "tcp"
+	ListenerUnix  = "unix"
+)
+
+// ListenerInfo configure a RPC listener, default listeners are replaced when set
+type ListenerInfo struct {
+	Name       string
+	Type       string
+	Hostname   string
+	Port       int
+	Seed       string
+	ClientAuth string
+	CertFile   string
+	KeyFile    string
+	SelfSigned bool
+	Path       string
+	Mode       string
+	Methods    []string
+	CORS       CORSInfo
+}
+
+type CORSInfo struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+}
+
+// HasOnionListener return true if tor is required by listeners
+func (p *Options) HasOnionListener() bool {
+	for _, listener := range p.Listeners {
+		if listener.Type == ListenerOnion {
+			return true
+		}
 	}
-	if i.Limit > 0 {
-		p.Limit = i.Limit
+	return false
+}
+
+type TorInfo struct {
+	ControlAddress  string
+	ControlPassword string
+	SocksAddress    string
+	DNSAddress      string
+	DataDir         string
+}
+
+// TTLInfo configure directory entries time to live by mode
+type TTLInfo struct {
+	Fast   time.Duration
+	Short  time.Duration
+	Normal time.Duration
+	Long   time.Duration
+}
+
+// Modes return durations by mode name
+func (p TTLInfo) Modes() map[string]time.Duration {
+	return map[string]time.Duration{
+		"fast":   p.Fast,
+		"short":  p.Short,
+		"normal": p.Normal,
+		"long":   p.Long,
 	}
 }
 
+type HeartbeatInfo struct {
+	Interval       time.Duration
+	StartupTimeout time.Duration
+	Timeout        time.Duration
+	Recovery       string
+}
+
 type IPCInfo struct {
 	Subject           string
 	ChildID           int
 	ChildProcessCount int
 	NatsHost          string
 	NatsPort          int
-}
-
-func (p *IPCInfo) Merge(i IPCInfo) {
-	if len(i.Subject) > 0 {
-		p.Subject = i.Subject
-	}
-	if i.ChildID > 0 {
-		p.ChildID = i.ChildID
-	}
-	if i.ChildProcessCount > 0 {
-		p.ChildProcessCount = i.ChildProcessCount
-	}
-	if len(i.NatsHost) > 0 {
-		p.NatsHost = i.NatsHost
-	}
-	if i.NatsPort > 0 {
-		p.NatsPort = i.NatsPort
-	}
+	// SecretsFd is the file descriptor of ChildSecrets, used by child processes
+	SecretsFd int `yaml:"-"`
 }
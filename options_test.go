@@ -0,0 +1,186 @@
+package soroban
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_OptionsLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	config := filepath.Join(dir, "soroban.yml")
+	err := os.WriteFile(config, []byte("soroban:\n  port: 0\n  withtor: false\np2p:\n  mdns: false\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := DefaultOptions
+	options.Soroban.Port = 4343
+	options.P2P.MDNS = true
+	err = options.LoadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Soroban.Port != 0 || options.P2P.MDNS || options.Soroban.Domain != DefaultOptions.Soroban.Domain {
+		t.Errorf("LoadFile() = %+v", options.Soroban)
+	}
+
+	unknown := filepath.Join(dir, "unknown.yml")
+	err = os.WriteFile(unknown, []byte("soroban:\n  prot: 4242\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := options.LoadFile(unknown); err == nil {
+		t.Error("LoadFile() unknown field must fail")
+	}
+
+	if err := options.LoadFile(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("LoadFile() missing file must fail")
+	}
+}
+
+func Test_OptionsValidate(t *testing.T) {
+	seed := "5baa80270886506c6b080de4e9558e2c32c50d3a7633f87d8396f5d5767e988d"
+
+	tests := []struct {
+		name    string
+		update  func(options *Options)
+		wantErr bool
+	}{
+		{"default", func(options *Options) {}, false},
+		{"seed", func(options *Options) { options.Soroban.WithTor = true; options.Soroban.Seed = seed }, false},
+		{"seed without tor", func(options *Options) { options.Soroban.Seed = seed }, true},
+		{"seed length", func(options *Options) { options.Soroban.WithTor = true; options.Soroban.Seed = seed[:32] }, true},
+		{"p2p seed auto", func(options *Options) { options.P2P.Seed = "auto" }, false},
+		{"p2p seed hex", func(options *Options) { options.P2P.Seed = "zz" }, true},
+		{"port", func(options *Options) { options.Soroban.Port = 65536 }, true},
+		{"admin port", func(options *Options) { options.Soroban.AdminPort = -1 }, true},
+		{"gossip dlo", func(options *Options) { options.Gossip.Dlo = options.Gossip.D + 1 }, true},
+		{"gossip dhi", func(options *Options) { options.Gossip.Dhi = options.Gossip.D - 1 }, true},
+		{"listener type", func(options *Options) { options.Listeners = []ListenerInfo{{Name: "a", Type: "udp"}} }, true},
+		{"log level", func(options *Options) { options.LogLevel = "verbose" }, true},
+		{"signing seed", func(options *Options) { options.Soroban.SigningSeed = seed }, false},
+		{"signing seed onion", func(options *Options) {
+			options.Soroban.WithTor = true
+			options.Soroban.Seed = seed
+			options.Soroban.SigningSeed = SigningSeedOnion
+		}, false},
+		{"signing seed onion without seed", func(options *Options) { options.Soroban.SigningSeed = SigningSeedOnion }, true},
+		{"signature window", func(options *Options) { options.Soroban.SignatureWindow = 0 }, true},
+		{"announce keys", func(options *Options) { options.Soroban.AnnounceKeys = seed + ", " + seed }, false},
+		{"announce keys hex", func(options *Options) { options.Soroban.AnnounceKeys = seed + ",zz" }, true},
+		{"transport", func(options *Options) { options.P2P.Transport = "tcp+tor" }, false},
+		{"transport unknown", func(options *Options) { options.P2P.Transport = "udp" }, true},
+		{"transport empty", func(options *Options) { options.P2P.Transport = "" }, true},
+		{"message format", func(options *Options) { options.P2P.MessageFormat = "json" }, true},
+		{"compression", func(options *Options) { options.P2P.Compression = "gzip" }, true},
+		{"shards", func(options *Options) { options.P2P.Shards = -1 }, true},
+		{"shard subscribe", func(options *Options) { options.P2P.Shards = 4; options.P2P.ShardSubscribe = "0, 3" }, false},
+		{"shard subscribe range", func(options *Options) { options.P2P.Shards = 4; options.P2P.ShardSubscribe = "4" }, true},
+		{"shard subscribe index", func(options *Options) { options.P2P.Shards = 4; options.P2P.ShardSubscribe = "a" }, true},
+		{"shard miss", func(options *Options) { options.P2P.ShardMiss = "drop" }, true},
+		{"recovery", func(options *Options) { options.Heartbeat.Recovery = "rebootstrap, restart" }, false},
+		{"recovery unknown", func(options *Options) { options.Heartbeat.Recovery = "rebootstrap,reboot" }, true},
+		{"recovery empty", func(options *Options) { options.Heartbeat.Recovery = "" }, true},
+		{"recovery custom", func(options *Options) { AddRecoveryAction("custom"); options.Heartbeat.Recovery = "custom" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultOptions
+			tt.update(&options)
+			if err := options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_OptionsRestartRequired(t *testing.T) {
+	options := DefaultOptions
+
+	reloaded := DefaultOptions
+	reloaded.LogLevel = "debug"
+	reloaded.Soroban.Announce = "announce"
+	reloaded.TTL.Short = 2 * time.Minute
+	reloaded.Gossip.D = 12
+	if restart := options.RestartRequired(reloaded); len(restart) != 0 {
+		t.Errorf("RestartRequired() = %v, want none", restart)
+	}
+
+	reloaded.Soroban.Port = 4343
+	reloaded.Listeners = []ListenerInfo{{Type: ListenerTCP, Port: 4343}}
+	restart := options.RestartRequired(reloaded)
+	if len(restart) != 2 || restart[0] != "soroban.port" || restart[1] != "listeners" {
+		t.Errorf("RestartRequired() = %v", restart)
+	}
+
+	applied := options.WithReloadable(reloaded)
+	if applied.Soroban.Port != options.Soroban.Port || applied.Gossip.D != 12 || applied.TTL.Short != 2*time.Minute {
+		t.Errorf("WithReloadable() = %+v", applied)
+	}
+}
+
+func Test_OptionsReloadableRestartRequired(t *testing.T) {
+	reloadable := ReloadableOptions{
+		LogLevel:         "debug",
+		Announce:         "announce",
+		AnnounceInterval: time.Hour,
+		AnnounceMode:     "long",
+		AnnounceUnsigned: !DefaultOptions.Soroban.AnnounceUnsigned,
+		AnnounceKeys:     strings.Repeat("a", SeedHexLength),
+		SignatureWindow:  time.Hour,
+		ReplayCacheSize:  42,
+		TTL:              TTLInfo{Short: time.Hour},
+		Gossip:           GossipInfo{D: 12},
+	}
+	// every reloadable option is changed
+	value := reflect.ValueOf(reloadable)
+	defaults := reflect.ValueOf(DefaultOptions.Reloadable())
+	for i := 0; i < value.NumField(); i++ {
+		if reflect.DeepEqual(value.Field(i).Interface(), defaults.Field(i).Interface()) {
+			t.Fatalf("ReloadableOptions.%s is not changed", value.Type().Field(i).Name)
+		}
+	}
+
+	options := DefaultOptions
+	if restart := options.RestartRequired(options.WithReloadableOptions(reloadable)); len(restart) != 0 {
+		t.Errorf("RestartRequired() = %v, want none", restart)
+	}
+}
+
+func Test_OptionsReloadable(t *testing.T) {
+	options := DefaultOptions
+	options.Soroban.Seed = strings.Repeat("a", SeedHexLength)
+	options.Soroban.SigningSeed = strings.Repeat("b", SeedHexLength)
+	options.P2P.Seed = strings.Repeat("c", SeedHexLength)
+	options.Tor.ControlPassword = "password"
+	options.LogLevel = "debug"
+	options.Gossip.D = 12
+
+	// reload messages sent to child processes must not include secrets
+	data, err := json.Marshal(options.Reloadable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{options.Soroban.Seed, options.Soroban.SigningSeed, options.P2P.Seed, options.Tor.ControlPassword} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Reloadable() contains secret %q", secret)
+		}
+	}
+
+	var reloaded ReloadableOptions
+	if err := json.Unmarshal(data, &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	applied := DefaultOptions.WithReloadableOptions(reloaded)
+	if applied.LogLevel != "debug" || applied.Gossip.D != 12 || len(applied.Soroban.Seed) != 0 {
+		t.Errorf("WithReloadableOptions() = %+v", applied)
+	}
+	if restart := applied.RestartRequired(DefaultOptions.WithReloadable(options)); len(restart) != 0 {
+		t.Errorf("RestartRequired() = %v, want none", restart)
+	}
+}
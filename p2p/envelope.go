@@ -0,0 +1,199 @@
+package p2p
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	MessageFormatLegacy   = "legacy"
+	MessageFormatEnvelope = "envelope"
+	MessageFormatAuto     = "auto"
+
+	CompressionNone = "none"
+	CompressionZstd = "zstd"
+
+	// EnvelopeVersion is the current envelope protocol version
+	EnvelopeVersion = 1
+
+	// envelopeMagic prefix envelope messages, legacy json messages always start with '{'
+	envelopeMagic = 0xb5
+
+	// payloads smaller than this are never compressed
+	compressionThreshold = 128
+	// maximum decompressed payload size
+	maxPayloadSize = 4 << 20
+)
+
+// Envelope protobuf fields
+const (
+	envelopeFieldID          protowire.Number = 1
+	envelopeFieldTimestamp   protowire.Number = 2
+	envelopeFieldOrigin      protowire.Number = 3
+	envelopeFieldContext     protowire.Number = 4
+	envelopeFieldCompression protowire.Number = 5
+	envelopeFieldPayload     protowire.Number = 6
+)
+
+const (
+	envelopeCompressionNone uint64 = 0
+	envelopeCompressionZstd uint64 = 1
+)
+
+var (
+	ErrInvalidEnvelope     = errors.New("invalid envelope")
+	ErrUnsupportedEnvelope = errors.New("unsupported envelope version")
+
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxPayloadSize))
+)
+
+func isEnvelope(data []byte) bool {
+	return len(data) > 1 && data[0] == envelopeMagic
+}
+
+// ToEnvelope encode message with versioned protobuf envelope.
+// Payload is compressed with zstd when compression is enabled and it reduce message size.
+func (p *Message) ToEnvelope(compression string) ([]byte, error) {
+	if len(p.Context) == 0 {
+		return nil, errors.New("invalid context")
+	}
+	if len(p.Payload) == 0 {
+		return nil, errors.New("invalid payload")
+	}
+
+	payload := p.Payload
+	payloadCompression := envelopeCompressionNone
+	if compression == CompressionZstd && len(payload) >= compressionThreshold {
+		compressed := zstdEncoder.EncodeAll(payload, nil)
+		if len(compressed) < len(payload) {
+			payload = compressed
+			payloadCompression = envelopeCompressionZstd
+		}
+	}
+
+	id, err := hex.DecodeString(p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message ID: %w", err)
+	}
+
+	data := []byte{envelopeMagic, EnvelopeVersion}
+	if len(id) > 0 {
+		data = protowire.AppendTag(data, envelopeFieldID, protowire.BytesType)
+		data = protowire.AppendBytes(data, id)
+	}
+	if p.Timestamp > 0 {
+		data = protowire.AppendTag(data, envelopeFieldTimestamp, protowire.VarintType)
+		data = protowire.AppendVarint(data, uint64(p.Timestamp))
+	}
+	if len(p.Origin) > 0 {
+		data = protowire.AppendTag(data, envelopeFieldOrigin, protowire.BytesType)
+		data = protowire.AppendString(data, p.Origin)
+	}
+	data = protowire.AppendTag(data, envelopeFieldContext, protowire.BytesType)
+	data = protowire.AppendString(data, p.Context)
+	if payloadCompression != envelopeCompressionNone {
+		data = protowire.AppendTag(data, envelopeFieldCompression, protowire.VarintType)
+		data = protowire.AppendVarint(data, payloadCompression)
+	}
+	data = protowire.AppendTag(data, envelopeFieldPayload, protowire.BytesType)
+	data = protowire.AppendBytes(data, payload)
+
+	return data, nil
+}
+
+// Encode message with requested format
+func (p *Message) Encode(format, compression string) ([]byte, error) {
+	switch format {
+	case MessageFormatLegacy:
+		return p.ToBytes()
+	default:
+		return p.ToEnvelope(compression)
+	}
+}
+
+func messageFromEnvelope(data []byte) (Message, error) {
+	if !isEnvelope(data) {
+		return Message{}, ErrInvalidEnvelope
+	}
+	if data[1] != EnvelopeVersion {
+		return Message{}, ErrUnsupportedEnvelope
+	}
+
+	var result Message
+	var payload []byte
+	compression := envelopeCompressionNone
+	err := walkEnvelope(data[2:], func(num protowire.Number, value []byte, varint uint64) bool {
+		switch num {
+		case envelopeFieldID:
+			result.ID = hex.EncodeToString(value)
+		case envelopeFieldTimestamp:
+			result.Timestamp = int64(varint)
+		case envelopeFieldOrigin:
+			result.Origin = string(value)
+		case envelopeFieldContext:
+			result.Context = string(value)
+		case envelopeFieldCompression:
+			compression = varint
+		case envelopeFieldPayload:
+			payload = value
+		}
+		return true
+	})
+	if err != nil {
+		return Message{}, err
+	}
+
+	switch compression {
+	case envelopeCompressionNone:
+		result.Payload = append([]byte(nil), payload...)
+	case envelopeCompressionZstd:
+		result.Payload, err = zstdDecoder.DecodeAll(payload, nil)
+		if err != nil {
+			return Message{}, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+	default:
+		return Message{}, errors.New("unknown envelope compression")
+	}
+
+	if len(result.Context) == 0 || len(result.Payload) == 0 {
+		return Message{}, ErrInvalidEnvelope
+	}
+
+	return result, nil
+}
+
+// walkEnvelope call fn for each known field, unknown fields are skipped for forward compatibility
+func walkEnvelope(data []byte, fn func(num protowire.Number, value []byte, varint uint64) bool) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return ErrInvalidEnvelope
+		}
+		data = data[n:]
+
+		var value []byte
+		var varint uint64
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return ErrInvalidEnvelope
+		}
+		data = data[n:]
+
+		if !fn(num, value, varint) {
+			return nil
+		}
+	}
+	return nil
+}
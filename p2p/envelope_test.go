@@ -0,0 +1,113 @@
+package p2p
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+)
+
+func Test_envelopeRoundTrip(t *testing.T) {
+	type args struct {
+		payload     string
+		compression string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"small", args{`{"Name":"key","Entry":"value"}`, CompressionZstd}},
+		{"compressed", args{`{"Name":"key","Entry":"` + strings.Repeat("value", 100) + `"}`, CompressionZstd}},
+		{"uncompressed", args{`{"Name":"key","Entry":"` + strings.Repeat("value", 100) + `"}`, CompressionNone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := Message{
+				Context:   "Directory.Add",
+				Payload:   []byte(tt.args.payload),
+				ID:        newMessageID(),
+				Origin:    "origin",
+				Timestamp: 42,
+			}
+			data, err := message.ToEnvelope(tt.args.compression)
+			if err != nil {
+				t.Fatalf("ToEnvelope() error = %v", err)
+			}
+
+			got, err := MessageFromBytes(data)
+			if err != nil {
+				t.Fatalf("MessageFromBytes() error = %v", err)
+			}
+			if got.Context != message.Context || got.ID != message.ID || got.Origin != message.Origin || got.Timestamp != message.Timestamp {
+				t.Errorf("MessageFromBytes() = %+v, want %+v", got, message)
+			}
+			if !bytes.Equal(got.Payload, message.Payload) {
+				t.Errorf("MessageFromBytes() payload = %s, want %s", got.Payload, message.Payload)
+			}
+		})
+	}
+}
+
+func Test_legacyMessage(t *testing.T) {
+	message, err := NewMessage("Directory.Add", map[string]string{"Name": "key"})
+	if err != nil {
+		t.Fatalf("NewMessage() error = %v", err)
+	}
+	data, err := message.Encode(MessageFormatLegacy, CompressionZstd)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if data[0] != '{' {
+		t.Errorf("Encode() legacy = %s", data)
+	}
+
+	got, err := MessageFromBytes([]byte(`{"Context":"Directory.Add","Payload":"eyJOYW1lIjoia2V5In0="}`))
+	if err != nil {
+		t.Fatalf("MessageFromBytes() error = %v", err)
+	}
+	if got.Context != "Directory.Add" || string(got.Payload) != `{"Name":"key"}` {
+		t.Errorf("MessageFromBytes() = %+v", got)
+	}
+}
+
+func Test_messageID(t *testing.T) {
+	message, err := NewMessage("Directory.Remove", map[string]string{"Name": "key", "Entry": strings.Repeat("value", 100)})
+	if err != nil {
+		t.Fatalf("NewMessage() error = %v", err)
+	}
+	data, err := message.Encode(MessageFormatEnvelope, CompressionZstd)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	// an other message reusing the ID must not be deduplicated
+	spoofed, err := NewMessage("Directory.Remove", map[string]string{"Name": "key", "Entry": "other"})
+	if err != nil {
+		t.Fatalf("NewMessage() error = %v", err)
+	}
+	spoofed.ID = message.ID
+	spoofedData, err := spoofed.Encode(MessageFormatEnvelope, CompressionZstd)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	id := messageID(&pb.Message{Data: data})
+	if id == messageID(&pb.Message{Data: spoofedData}) {
+		t.Errorf("messageID() must differ for messages reusing an ID")
+	}
+	if id != messageID(&pb.Message{Data: append([]byte(nil), data...)}) {
+		t.Errorf("messageID() must be stable for the same message")
+	}
+
+	// the same message is deduplicated when encoded with an other format or compression
+	for _, format := range [][2]string{{MessageFormatEnvelope, CompressionNone}, {MessageFormatLegacy, CompressionNone}} {
+		other, err := message.Encode(format[0], format[1])
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		if bytes.Equal(other, data) || messageID(&pb.Message{Data: other}) != id {
+			t.Errorf("messageID() must be the same for %s message", format)
+		}
+	}
+}
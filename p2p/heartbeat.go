@@ -0,0 +1,84 @@
+package p2p
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// HeartbeatContext is the message context for p2p heartbeats
+const HeartbeatContext = "P2P.Heartbeat"
+
+// LegacyHeartbeatKey is the directory key used by heartbeats of older nodes
+const LegacyHeartbeatKey = "p2p.heartbeat"
+
+// maximum number of nodes kept in heartbeat status
+const maxHeartbeatNodes = 256
+
+// HeartbeatLoad indicate node load
+type HeartbeatLoad struct {
+	Peers      int `json:"peers"`
+	Goroutines int `json:"goroutines"`
+}
+
+// Heartbeat message payload
+type Heartbeat struct {
+	Version   string        `json:"version"`
+	Timestamp int64         `json:"timestamp"`
+	Load      HeartbeatLoad `json:"load"`
+}
+
+// NodeHeartbeat is the last heartbeat received from a node
+type NodeHeartbeat struct {
+	Origin     string        `json:"origin"`
+	Version    string        `json:"version"`
+	Load       HeartbeatLoad `json:"load"`
+	ReceivedAt time.Time     `json:"received_at"`
+}
+
+// PublishHeartbeat publish heartbeat with node version and load on room topic
+func (p *P2P) PublishHeartbeat(ctx context.Context, version string) error {
+	load := HeartbeatLoad{
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if n := p.started(); n != nil {
+		load.Peers = len(n.host.Network().Peers())
+	}
+
+	return p.PublishJson(ctx, HeartbeatContext, Heartbeat{
+		Version:   version,
+		Timestamp: time.Now().Unix(),
+		Load:      load,
+	})
+}
+
+// RecordNodeHeartbeat store heartbeat received from origin node, origin is the pubsub authenticated author
+func (p *P2P) RecordNodeHeartbeat(origin string, heartbeat Heartbeat) {
+	p.RecordHeartbeat()
+	if len(origin) == 0 {
+		return
+	}
+
+	p.statusMtx.Lock()
+	defer p.statusMtx.Unlock()
+
+	if p.nodes == nil {
+		p.nodes = make(map[string]NodeHeartbeat)
+	}
+	if _, ok := p.nodes[origin]; !ok && len(p.nodes) >= maxHeartbeatNodes {
+		// evict oldest node
+		var oldest string
+		for key, node := range p.nodes {
+			if len(oldest) == 0 || node.ReceivedAt.Before(p.nodes[oldest].ReceivedAt) {
+				oldest = key
+			}
+		}
+		delete(p.nodes, oldest)
+	}
+	p.nodes[origin] = NodeHeartbeat{
+		Origin:     origin,
+		Version:    heartbeat.Version,
+		Load:       heartbeat.Load,
+		ReceivedAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,261 @@
+package p2p
+
+import (
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"github.com/cretz/bine/torutil"
+	torEd25519 "github.com/cretz/bine/torutil/ed25519"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// IdentityPassphraseEnv is the environment variable used to decrypt identity file
+const IdentityPassphraseEnv = "SOROBAN_P2P_PASSPHRASE"
+
+// IdentityVersion of the identity file schema
+const IdentityVersion = 1
+
+// scrypt parameters for identity encryption
+const (
+	identityScryptN = 1 << 15
+	identityScryptR = 8
+	identityScryptP = 1
+)
+
+var (
+	ErrIdentityPermissions = errors.New("identity file must not be accessible by group or others")
+	ErrIdentityPassphrase  = errors.New("invalid identity passphrase")
+)
+
+// Identity hold the seed of libp2p and onion keys
+type Identity struct {
+	Seed []byte
+}
+
+// IdentityEncryption is the scrypt + chacha20poly1305 encrypted seed
+type IdentityEncryption struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// IdentityFile is the identity file content, seed is set only if not encrypted
+type IdentityFile struct {
+	Version    int                 `json:"version"`
+	PeerID     string              `json:"peer_id"`
+	Onion      string              `json:"onion"`
+	Seed       string              `json:"seed,omitempty"`
+	Encryption *IdentityEncryption `json:"encryption,omitempty"`
+}
+
+// NewIdentity generate a random identity
+func NewIdentity() (Identity, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	_, err := rand.Read(seed)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Seed: seed}, nil
+}
+
+// Hex return seed as used by --p2pSeed
+func (p Identity) Hex() string {
+	return hex.EncodeToString(p.Seed)
+}
+
+// PeerID return libp2p peer ID
+func (p Identity) PeerID() (peer.ID, error) {
+	priv, _, err := keysFromSeed(p.Hex())
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPrivateKey(priv)
+}
+
+// Onion return onion service ID
+func (p Identity) Onion() string {
+	privateKey := ed25519.NewKeyFromSeed(p.Seed)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	return torutil.OnionServiceIDFromV3PublicKey(torEd25519.PublicKey(publicKey))
+}
+
+// IdentityLoadOrCreate load identity file or create it with a random identity.
+// File is encrypted when passphrase is not empty.
+func IdentityLoadOrCreate(filename, passphrase string) (Identity, error) {
+	identity, err := IdentityLoad(filename, passphrase)
+	if !errors.Is(err, os.ErrNotExist) {
+		return identity, err
+	}
+
+	identity, err = NewIdentity()
+	if err != nil {
+		return Identity{}, err
+	}
+	err = IdentitySave(filename, identity, passphrase)
+	if err != nil {
+		return Identity{}, err
+	}
+	return identity, nil
+}
+
+// IdentityLoad read identity file, file permissions are checked
+func IdentityLoad(filename, passphrase string) (Identity, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return Identity{}, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return Identity{}, ErrIdentityPermissions
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Identity{}, err
+	}
+	var file IdentityFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return Identity{}, err
+	}
+	if file.Version != IdentityVersion {
+		return Identity{}, fmt.Errorf("unsupported identity version: %d", file.Version)
+	}
+
+	var seed []byte
+	if file.Encryption != nil {
+		if len(passphrase) == 0 {
+			return Identity{}, fmt.Errorf("identity file is encrypted, set %s", IdentityPassphraseEnv)
+		}
+		seed, err = file.Encryption.decrypt(passphrase)
+	} else {
+		seed, err = hex.DecodeString(file.Seed)
+	}
+	if err != nil {
+		return Identity{}, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return Identity{}, errors.New("invalid identity seed length")
+	}
+	return Identity{Seed: seed}, nil
+}
+
+// IdentitySave write identity file with owner only permissions
+func IdentitySave(filename string, identity Identity, passphrase string) error {
+	peerID, err := identity.PeerID()
+	if err != nil {
+		return err
+	}
+	file := IdentityFile{
+		Version: IdentityVersion,
+		PeerID:  peerID.String(),
+		Onion:   identity.Onion(),
+	}
+	if len(passphrase) > 0 {
+		file.Encryption, err = encryptIdentity(identity.Seed, passphrase)
+		if err != nil {
+			return err
+		}
+	} else {
+		file.Seed = identity.Hex()
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	// temporary file is created with 0600 permissions
+	return writeFileAtomic(filename, append(data, '\n'))
+}
+
+func encryptIdentity(seed []byte, passphrase string) (*IdentityEncryption, error) {
+	salt := make([]byte, 16)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	result := IdentityEncryption{
+		KDF:   "scrypt",
+		N:     identityScryptN,
+		R:     identityScryptR,
+		P:     identityScryptP,
+		Salt:  hex.EncodeToString(salt),
+		Nonce: hex.EncodeToString(nonce),
+	}
+	aead, err := result.aead(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	result.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, seed, nil))
+	return &result, nil
+}
+
+func (p *IdentityEncryption) decrypt(passphrase string) ([]byte, error) {
+	if p.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported identity kdf: %s", p.KDF)
+	}
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(p.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(p.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := p.aead(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid identity nonce")
+	}
+	seed, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrIdentityPassphrase
+	}
+	return seed, nil
+}
+
+func (p *IdentityEncryption) aead(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+// ResolveSeed return p2p seed from identity file or seed option
+func ResolveSeed(optionsP2P soroban.P2PInfo) (string, error) {
+	if len(optionsP2P.IdentityFile) > 0 {
+		if len(optionsP2P.Seed) > 0 {
+			return "", errors.New("p2p seed and identity file can't be used together")
+		}
+		identity, err := IdentityLoadOrCreate(optionsP2P.IdentityFile, os.Getenv(IdentityPassphraseEnv))
+		if err != nil {
+			return "", err
+		}
+		return identity.Hex(), nil
+	}
+	return optionsP2P.Seed, nil
+}
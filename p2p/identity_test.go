@@ -0,0 +1,46 @@
+package p2p
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_identityLoadOrCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"plain", ""},
+		{"encrypted", "passphrase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "identity.json")
+
+			created, err := IdentityLoadOrCreate(filename, tt.passphrase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := IdentityLoadOrCreate(filename, tt.passphrase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.Hex() != loaded.Hex() {
+				t.Errorf("IdentityLoadOrCreate() = %s, want %s", loaded.Hex(), created.Hex())
+			}
+
+			if len(tt.passphrase) > 0 {
+				if _, err := IdentityLoad(filename, "wrong"); !errors.Is(err, ErrIdentityPassphrase) {
+					t.Errorf("IdentityLoad() error = %v, want %v", err, ErrIdentityPassphrase)
+				}
+			}
+
+			os.Chmod(filename, 0644)
+			if _, err := IdentityLoad(filename, tt.passphrase); !errors.Is(err, ErrIdentityPermissions) {
+				t.Errorf("IdentityLoad() error = %v, want %v", err, ErrIdentityPermissions)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package p2p
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type mdnsNotifee struct {
+	ctx  context.Context
+	host host.Host
+}
+
+// HandlePeerFound connect to peers discovered on local network.
+// It is called from mDNS resolver loop and must not block.
+func (n *mdnsNotifee) HandlePeerFound(info peer.AddrInfo) {
+	log.WithField("Peer", info.ID).Debug("mDNS peer found")
+	if info.ID == n.host.ID() {
+		return
+	}
+	go n.connect(info)
+}
+
+func (n *mdnsNotifee) connect(info peer.AddrInfo) {
+	ctx, cancel := context.WithTimeout(n.ctx, 30*time.Second)
+	defer cancel()
+	if err := n.host.Connect(ctx, info); err != nil {
+		log.WithError(err).WithField("Peer", info.ID).Debug("Failed to connect mDNS peer")
+	}
+}
+
+// startMDNS start local peer discovery, service name is derived from room
+// so nodes from different rooms don't connect each others.
+func startMDNS(ctx context.Context, h host.Host, room string) error {
+	service := mdns.NewMdnsService(h, mdnsServiceName(room), &mdnsNotifee{
+		ctx:  ctx,
+		host: h,
+	})
+	err := service.Start()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		service.Close()
+	}()
+	return nil
+}
+
+// mdnsServiceName return service name from room, limited to 15 characters (RFC 6335)
+func mdnsServiceName(room string) string {
+	hash := sha256.Sum256([]byte(room))
+	return "_soroban" + hex.EncodeToString(hash[:4]) + "._udp"
+}
@@ -0,0 +1,17 @@
+package p2p
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_mdnsServiceName(t *testing.T) {
+	name := mdnsServiceName("room")
+	service := strings.TrimSuffix(strings.TrimPrefix(name, "_"), "._udp")
+	if len(service) > 15 || service == name {
+		t.Errorf("mdnsServiceName() = %s", name)
+	}
+	if mdnsServiceName("room") != name || mdnsServiceName("other") == name {
+		t.Error("mdnsServiceName() must be derived from room")
+	}
+}
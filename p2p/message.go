@@ -1,13 +1,19 @@
 package p2p
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 type Message struct {
-	Context string
-	Payload []byte
+	Context   string
+	Payload   []byte
+	ID        string `json:",omitempty"`
+	Origin    string `json:",omitempty"`
+	Timestamp int64  `json:",omitempty"`
 }
 
 func NewMessage(context string, obj interface{}) (Message, error) {
@@ -23,16 +29,23 @@ func NewMessage(context string, obj interface{}) (Message, error) {
 	}
 
 	return Message{
-		Context: context,
-		Payload: payload,
+		Context:   context,
+		Payload:   payload,
+		ID:        newMessageID(),
+		Timestamp: time.Now().UnixNano(),
 	}, nil
 }
 
+// MessageFromBytes decode message from envelope or legacy json format
 func MessageFromBytes(data []byte) (Message, error) {
 	if len(data) == 0 {
 		return Message{}, errors.New("invalid data")
 	}
 
+	if isEnvelope(data) {
+		return messageFromEnvelope(data)
+	}
+
 	var result Message
 	err := json.Unmarshal(data, &result)
 	if err != nil {
@@ -42,6 +55,7 @@ func MessageFromBytes(data []byte) (Message, error) {
 	return result, nil
 }
 
+// ToBytes encode message with legacy json format
 func (p *Message) ToBytes() ([]byte, error) {
 	if len(p.Context) == 0 {
 		return nil, errors.New("invalid context")
@@ -66,3 +80,12 @@ func (p *Message) ParsePayload(obj interface{}) error {
 
 	return json.Unmarshal(p.Payload, obj)
 }
+
+func newMessageID() string {
+	var id [16]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,338 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PeerstoreVersion of the persisted peerstore schema
+const PeerstoreVersion = 1
+
+// PersistedPeer is a peer entry of the persisted peerstore
+type PersistedPeer struct {
+	ID        string    `json:"id"`
+	Addrs     []string  `json:"addrs"`
+	Protocols []string  `json:"protocols,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty"`
+	Successes int       `json:"successes"`
+	Failures  int       `json:"failures"`
+}
+
+// PersistedPeerstore is the versioned peerstore file content
+type PersistedPeerstore struct {
+	Version int             `json:"version"`
+	Peers   []PersistedPeer `json:"peers"`
+}
+
+// maxPeerStats limit peers stats kept in memory, least recently seen peers are evicted
+const maxPeerStats = 1024
+
+type peerStat struct {
+	lastSeen  time.Time
+	successes int
+	failures  int
+}
+
+// score used to select peers on startup, recently reliable peers first
+func (p PersistedPeer) score(now time.Time) float64 {
+	reliability := float64(p.Successes+1) / float64(p.Successes+p.Failures+2)
+	if p.LastSeen.IsZero() {
+		return reliability / 2
+	}
+	// decrease by half every day since last seen
+	age := now.Sub(p.LastSeen).Hours() / 24
+	return reliability / (1 + age)
+}
+
+func (p *P2P) recordPeerConnected(peerID peer.ID) {
+	p.peerStatsMtx.Lock()
+	defer p.peerStatsMtx.Unlock()
+
+	stat := p.peerStat(peerID)
+	stat.lastSeen = time.Now().UTC()
+	stat.successes++
+}
+
+func (p *P2P) recordPeerFailure(peerID peer.ID) {
+	p.peerStatsMtx.Lock()
+	defer p.peerStatsMtx.Unlock()
+
+	p.peerStat(peerID).failures++
+}
+
+// peerStat must be called with peerStatsMtx locked
+func (p *P2P) peerStat(peerID peer.ID) *peerStat {
+	if p.peerStats == nil {
+		p.peerStats = make(map[peer.ID]*peerStat)
+	}
+	stat, ok := p.peerStats[peerID]
+	if !ok {
+		if len(p.peerStats) >= maxPeerStats {
+			p.evictPeerStat()
+		}
+		stat = &peerStat{}
+		p.peerStats[peerID] = stat
+	}
+	return stat
+}
+
+// evictPeerStat remove least recently seen peer, must be called with peerStatsMtx locked
+func (p *P2P) evictPeerStat() {
+	var oldestID peer.ID
+	var oldest *peerStat
+	for peerID, stat := range p.peerStats {
+		if oldest == nil || stat.lastSeen.Before(oldest.lastSeen) {
+			oldestID, oldest = peerID, stat
+		}
+	}
+	delete(p.peerStats, oldestID)
+}
+
+// Persist the Peerstore
+func StartPeerstorePersistence(ctx context.Context, optionsP2P soroban.P2PInfo, p *P2P) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Exiting peerstore persistence Loop")
+			return
+		case <-ticker.C:
+			err := p.PersistPeerstore(ctx, optionsP2P)
+			if err != nil {
+				log.WithError(err).Warning("Failed to persist peerstore")
+			}
+		}
+	}
+}
+
+func (p *P2P) PersistPeerstore(ctx context.Context, optionsP2P soroban.P2PInfo) error {
+	n := p.started()
+	if n == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	connected := make(map[peer.ID]bool)
+	for _, peerID := range n.host.Network().Peers() {
+		connected[peerID] = true
+	}
+
+	peerstore := n.host.Network().Peerstore()
+	result := PersistedPeerstore{
+		Version: PeerstoreVersion,
+		Peers:   make([]PersistedPeer, 0),
+	}
+
+	p.peerStatsMtx.Lock()
+	known := make(map[peer.ID]bool)
+	for _, peerID := range peerstore.PeersWithAddrs() {
+		if n.host.ID() == peerID {
+			continue
+		}
+		peerInfo := peerstore.PeerInfo(peerID)
+		if len(peerInfo.Addrs) == 0 {
+			continue
+		}
+		known[peerID] = true
+
+		stat := p.peerStat(peerID)
+		if connected[peerID] {
+			stat.lastSeen = now
+		}
+
+		entry := PersistedPeer{
+			ID:        peerID.String(),
+			LastSeen:  stat.lastSeen,
+			Successes: stat.successes,
+			Failures:  stat.failures,
+		}
+		for _, addr := range peerInfo.Addrs {
+			entry.Addrs = append(entry.Addrs, addr.String())
+		}
+		if protocols, err := peerstore.GetProtocols(peerID); err == nil {
+			for _, protocol := range protocols {
+				entry.Protocols = append(entry.Protocols, string(protocol))
+			}
+			sort.Strings(entry.Protocols)
+		}
+		result.Peers = append(result.Peers, entry)
+	}
+	// prune stats of peers removed from peerstore
+	for peerID := range p.peerStats {
+		if !known[peerID] {
+			delete(p.peerStats, peerID)
+		}
+	}
+	p.peerStatsMtx.Unlock()
+
+	if len(result.Peers) == 0 {
+		p.recordPeerstore(0, nil)
+		return nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		p.recordPeerstore(len(result.Peers), err)
+		return err
+	}
+	err = writeFileAtomic(peerstoreFile(optionsP2P.PeerstoreFile, p.ChildID), append(data, '\n'))
+	p.recordPeerstore(len(result.Peers), err)
+
+	return err
+}
+
+func peerstoreFile(prefix string, childID int) string {
+	return fmt.Sprintf(prefix+".c%d.json", childID)
+}
+
+// writeFileAtomic write data to a temporary file and rename it to filename
+func writeFileAtomic(filename string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := file.Name()
+	defer os.Remove(tmpName)
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, filename)
+}
+
+// readPeerstore load persisted peers, legacy AddrInfo list is also supported
+func readPeerstore(filename string) ([]PersistedPeer, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var result PersistedPeerstore
+	if err := json.Unmarshal(data, &result); err == nil {
+		if result.Version > PeerstoreVersion {
+			return nil, fmt.Errorf("unsupported peerstore version: %d", result.Version)
+		}
+		return result.Peers, nil
+	}
+
+	var legacy []peer.AddrInfo
+	err = json.Unmarshal(data, &legacy)
+	if err != nil {
+		return nil, err
+	}
+	var peers []PersistedPeer
+	for _, info := range legacy {
+		entry := PersistedPeer{
+			ID: info.ID.String(),
+		}
+		for _, addr := range info.Addrs {
+			entry.Addrs = append(entry.Addrs, addr.String())
+		}
+		peers = append(peers, entry)
+	}
+	return peers, nil
+}
+
+// ConnectToPersistedPeers connect started node to peers from the persisted peerstore
+func (p *P2P) ConnectToPersistedPeers(ctx context.Context, optionsP2P soroban.P2PInfo) error {
+	n := p.started()
+	if n == nil {
+		return errors.New("p2p not started")
+	}
+	return p.connectToPersistedPeers(ctx, n.host, optionsP2P)
+}
+
+func (p *P2P) connectToPersistedPeers(ctx context.Context, h host.Host, optionsP2P soroban.P2PInfo) error {
+	filename := peerstoreFile(optionsP2P.PeerstoreFile, p.ChildID)
+	peers, err := readPeerstore(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		// corrupted peerstore is not fatal, nodes can still bootstrap
+		log.WithError(err).WithField("File", filename).Warning("Failed to read peerstore")
+		return nil
+	}
+
+	// restore stats and select recently reliable peers first
+	now := time.Now().UTC()
+	var peersAddrs []peer.AddrInfo
+	sort.SliceStable(peers, func(i, j int) bool {
+		return peers[i].score(now) > peers[j].score(now)
+	})
+	p.peerStatsMtx.Lock()
+	for _, entry := range peers {
+		peerID, err := peer.Decode(entry.ID)
+		if err != nil || peerID == h.ID() {
+			continue
+		}
+		info := peer.AddrInfo{ID: peerID}
+		for _, addr := range entry.Addrs {
+			maddr, err := multiaddr.NewMultiaddr(addr)
+			if err != nil {
+				continue
+			}
+			info.Addrs = append(info.Addrs, maddr)
+		}
+		if len(info.Addrs) == 0 {
+			continue
+		}
+
+		// stats are only restored once, peerstore can be reloaded by recovery
+		if _, ok := p.peerStats[peerID]; !ok {
+			stat := p.peerStat(peerID)
+			stat.lastSeen = entry.LastSeen
+			stat.successes = entry.Successes
+			stat.failures = entry.Failures
+		}
+		peersAddrs = append(peersAddrs, info)
+	}
+	p.peerStatsMtx.Unlock()
+
+	maxNbSelectedPeers := optionsP2P.LowWater / 2
+	if len(peersAddrs) > maxNbSelectedPeers {
+		peersAddrs = peersAddrs[:maxNbSelectedPeers+1]
+	}
+
+	if len(peersAddrs) > 0 {
+		var wg sync.WaitGroup
+		for _, peerinfo := range peersAddrs {
+			wg.Add(1)
+			go func(peerinfo peer.AddrInfo) {
+				log.Debugf("Boostrapping attempt with %v", peerinfo)
+				defer wg.Done()
+				if err := h.Connect(ctx, peerinfo); err != nil {
+					p.recordPeerFailure(peerinfo.ID)
+					log.WithError(err).Warnf("Bootstrap warning: %v", peerinfo)
+				}
+			}(peerinfo)
+		}
+		wg.Wait()
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func Test_readPeerstore(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy := filepath.Join(dir, "legacy.json")
+	err := os.WriteFile(legacy, []byte(`[{"ID":"16Uiu2HAm8oFK2aJHKMWMeUCzjH65smgw15zgBLkKSPDfmKqu6Y7f","Addrs":["/ip4/127.0.0.1/tcp/1042"]}]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers, err := readPeerstore(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || len(peers[0].Addrs) != 1 || peers[0].Addrs[0] != "/ip4/127.0.0.1/tcp/1042" {
+		t.Errorf("readPeerstore() legacy = %v", peers)
+	}
+
+	versioned := filepath.Join(dir, "versioned.json")
+	data, _ := json.Marshal(PersistedPeerstore{
+		Version: PeerstoreVersion,
+		Peers: []PersistedPeer{
+			{ID: "a", Addrs: []string{"/ip4/127.0.0.1/tcp/1042"}, Successes: 3, LastSeen: time.Now().UTC()},
+		},
+	})
+	err = writeFileAtomic(versioned, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers, err = readPeerstore(versioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].Successes != 3 {
+		t.Errorf("readPeerstore() versioned = %v", peers)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("temporary file not removed: %d entries", len(entries))
+	}
+
+	corrupted := filepath.Join(dir, "corrupted.json")
+	os.WriteFile(corrupted, []byte(`{"version":1,"peers":[{"id"`), 0600)
+	if _, err := readPeerstore(corrupted); err == nil {
+		t.Error("readPeerstore() corrupted file should fail")
+	}
+}
+
+func Test_persistedPeerScore(t *testing.T) {
+	now := time.Now()
+	recent := PersistedPeer{Successes: 5, LastSeen: now.Add(-time.Hour)}
+	old := PersistedPeer{Successes: 5, LastSeen: now.Add(-72 * time.Hour)}
+	unreliable := PersistedPeer{Successes: 1, Failures: 9, LastSeen: now.Add(-time.Hour)}
+
+	if recent.score(now) <= old.score(now) {
+		t.Error("recent peer should be preferred")
+	}
+	if recent.score(now) <= unreliable.score(now) {
+		t.Error("reliable peer should be preferred")
+	}
+}
+
+func Test_peerStatsLimit(t *testing.T) {
+	var p P2P
+	p.recordPeerConnected(peer.ID("recent"))
+	for i := 0; i < maxPeerStats+10; i++ {
+		p.recordPeerFailure(peer.ID(fmt.Sprintf("peer-%d", i)))
+	}
+	p.recordPeerConnected(peer.ID("recent"))
+
+	if len(p.peerStats) != maxPeerStats {
+		t.Errorf("peerStats size = %d", len(p.peerStats))
+	}
+	if stat, ok := p.peerStats[peer.ID("recent")]; !ok || stat.successes != 2 {
+		t.Errorf("recently seen peer evicted: %v", stat)
+	}
+}
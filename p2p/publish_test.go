@@ -0,0 +1,164 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+)
+
+func newTestHost(t *testing.T) host.Host {
+	h, err := libp2p.New(
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		libp2p.Transport(tcp.NewTCPTransport),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+// joinTestTopics subscribe host to topics
+func joinTestTopics(t *testing.T, ctx context.Context, h host.Host, names ...string) []*pubsub.Topic {
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var topics []*pubsub.Topic
+	for _, name := range names {
+		topic, err := ps.Join(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := topic.Subscribe(); err != nil {
+			t.Fatal(err)
+		}
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+func Test_publishFormatTopic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	local := newTestHost(t)
+	legacy := newTestHost(t)
+	envelope := newTestHost(t)
+	envelope.SetStreamHandler(EnvelopeProtocolID, func(s network.Stream) { s.Close() })
+
+	// legacy peer is only subscribed to room topic
+	topics := joinTestTopics(t, ctx, local, "room", "shard")
+	joinTestTopics(t, ctx, legacy, "room")
+	joinTestTopics(t, ctx, envelope, "room", "shard")
+	for _, h := range []host.Host{legacy, envelope} {
+		if err := local.Connect(ctx, peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	room, shard := topics[0], topics[1]
+	deadline := time.Now().Add(10 * time.Second)
+	for len(room.ListPeers()) != 2 || len(shard.ListPeers()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("topic peers = %d, %d", len(room.ListPeers()), len(shard.ListPeers()))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	n := &node{host: local, topic: room, messageFormat: MessageFormatAuto}
+	if format := n.publishFormat(room); format != MessageFormatLegacy {
+		t.Errorf("publishFormat() room = %s", format)
+	}
+	if format := n.publishFormat(shard); format != MessageFormatEnvelope {
+		t.Errorf("publishFormat() shard = %s", format)
+	}
+}
+
+func Test_subscribeOrigin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	local := newTestHost(t)
+	remote := newTestHost(t)
+	topic := joinTestTopics(t, ctx, remote, "room")[0]
+
+	ps, err := pubsub.NewGossipSub(ctx, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localTopic, err := ps.Join("room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriber, err := localTopic.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &P2P{OnMessage: make(chan Message, 10)}
+	go p.subscribe(ctx, local.ID(), subscriber)
+
+	if err := local.Connect(ctx, peer.AddrInfo{ID: remote.ID(), Addrs: remote.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(origin string) {
+		message, err := NewMessage(HeartbeatContext, Heartbeat{Version: origin})
+		if err != nil {
+			t.Fatal(err)
+		}
+		message.Origin = origin
+		data, err := message.Encode(MessageFormatEnvelope, CompressionNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := topic.Publish(ctx, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// wait until messages are delivered
+	deadline := time.Now().Add(10 * time.Second)
+	for ready := false; !ready; {
+		if time.Now().After(deadline) {
+			t.Fatal("message not received")
+		}
+		publish(remote.ID().String())
+		select {
+		case <-p.OnMessage:
+			ready = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for len(p.OnMessage) > 0 {
+		<-p.OnMessage
+	}
+
+	publish(local.ID().String()) // spoofed origin
+	publish("")
+	publish(remote.ID().String())
+
+	for i := 0; i < 2; i++ {
+		select {
+		case message := <-p.OnMessage:
+			if message.Origin != remote.ID().String() {
+				t.Errorf("subscribe() origin = %s", message.Origin)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("message %d not received", i)
+		}
+	}
+	select {
+	case message := <-p.OnMessage:
+		t.Errorf("subscribe() unexpected message = %+v", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
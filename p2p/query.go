@@ -0,0 +1,127 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// QueryProtocolID is used to query peers for keys outside of node shards
+const QueryProtocolID = protocol.ID("/soroban/query/1.0.0")
+
+const (
+	queryTimeout  = 30 * time.Second
+	queryMaxPeers = 3
+	maxQuerySize  = 1 << 20
+)
+
+var (
+	ErrNoShardPeer = errors.New("no peer found for shard")
+)
+
+// QueryHandler process query from remote peer
+type QueryHandler func(ctx context.Context, request []byte) ([]byte, error)
+
+// HasShard return true if key can be answered from the local directory
+func (p *P2P) HasShard(key string) bool {
+	if p.Sharding.Miss == ShardMissLocal {
+		return true
+	}
+	return p.Sharding.Subscribed(p.Sharding.Index(p.Domain, key))
+}
+
+// Query send request to a few peers subscribed to key shard, first response is returned
+func (p *P2P) Query(ctx context.Context, key string, request []byte) ([]byte, error) {
+	n := p.started()
+	if n == nil {
+		return nil, errors.New("p2p not started")
+	}
+	topic := p.shardTopic(n, key)
+	if topic == nil {
+		return nil, ErrNoShardPeer
+	}
+
+	peers := topic.ListPeers()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > queryMaxPeers {
+		peers = peers[:queryMaxPeers]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	err := ErrNoShardPeer
+	for _, peerID := range peers {
+		var response []byte
+		response, err = queryPeer(ctx, n.host, peerID, request)
+		if err != nil {
+			log.WithError(err).WithField("Peer", peerID).Debug("Query failed")
+			continue
+		}
+		return response, nil
+	}
+	return nil, err
+}
+
+func queryPeer(ctx context.Context, h host.Host, peerID peer.ID, request []byte) ([]byte, error) {
+	s, err := h.NewStream(ctx, peerID, QueryProtocolID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+
+	_, err = s.Write(request)
+	if err != nil {
+		s.Reset()
+		return nil, err
+	}
+	err = s.CloseWrite()
+	if err != nil {
+		s.Reset()
+		return nil, err
+	}
+
+	return io.ReadAll(io.LimitReader(s, maxQuerySize))
+}
+
+func (p *P2P) handleQuery(ctx context.Context, s network.Stream) {
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(queryTimeout))
+
+	request, err := io.ReadAll(io.LimitReader(s, maxQuerySize))
+	if err != nil {
+		log.WithError(err).Debug("Failed to read query")
+		s.Reset()
+		return
+	}
+
+	if p.OnQuery == nil {
+		s.Reset()
+		return
+	}
+	response, err := p.OnQuery(ctx, request)
+	if err != nil {
+		log.WithError(err).Debug("Failed to process query")
+		s.Reset()
+		return
+	}
+
+	_, err = s.Write(response)
+	if err != nil {
+		log.WithError(err).Debug("Failed to write query response")
+		s.Reset()
+	}
+}
@@ -0,0 +1,109 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Recovery actions when no heartbeat is received
+const (
+	RecoveryExit        = "exit"
+	RecoveryRebootstrap = "rebootstrap"
+	RecoveryReconnect   = "reconnect"
+	RecoveryRestart     = "restart"
+)
+
+// RecoveryFunc try to restore p2p connectivity
+type RecoveryFunc func(ctx context.Context, p *P2P) error
+
+var (
+	recoveryMtx sync.Mutex
+	recoveries  = map[string]RecoveryFunc{
+		RecoveryExit:        recoverExit,
+		RecoveryRebootstrap: recoverRebootstrap,
+		RecoveryReconnect:   recoverReconnect,
+		RecoveryRestart:     recoverRestart,
+	}
+)
+
+// RegisterRecovery add or replace a recovery action
+func RegisterRecovery(name string, fn RecoveryFunc) {
+	recoveryMtx.Lock()
+	defer recoveryMtx.Unlock()
+
+	recoveries[name] = fn
+	soroban.AddRecoveryAction(name)
+}
+
+// Recover run recovery action
+func (p *P2P) Recover(ctx context.Context, action string) error {
+	recoveryMtx.Lock()
+	fn, ok := recoveries[action]
+	recoveryMtx.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown recovery action: %s", action)
+	}
+
+	return fn(ctx, p)
+}
+
+// recoverExit shutdown tor clients and exit process
+func recoverExit(ctx context.Context, p *P2P) error {
+	soroban.Shutdown(ctx)
+	os.Exit(0)
+	return nil
+}
+
+// recoverRebootstrap reconnect to bootstrap peers and bootstrap the DHT
+func recoverRebootstrap(ctx context.Context, p *P2P) error {
+	n := p.started()
+	if n == nil || n.dht == nil {
+		return errors.New("p2p not started")
+	}
+
+	for _, addr := range n.bootstrapPeers {
+		info, err := peer.AddrInfoFromP2pAddr(addr)
+		if err != nil || info.ID == n.host.ID() {
+			continue
+		}
+		if err := n.host.Connect(ctx, *info); err != nil {
+			log.WithError(err).Warnf("Bootstrap warning: %v", info)
+		}
+	}
+
+	return n.dht.Bootstrap(ctx)
+}
+
+// recoverReconnect connect to peers from the persisted peerstore
+func recoverReconnect(ctx context.Context, p *P2P) error {
+	p.restartMtx.Lock()
+	options := p.options
+	p.restartMtx.Unlock()
+	if options.PeerstoreFile == "-" {
+		return errors.New("peerstore persistence disabled")
+	}
+
+	return p.ConnectToPersistedPeers(ctx, options)
+}
+
+// recoverRestart restart the p2p subsystem, process exit if p2p can't be started
+func recoverRestart(ctx context.Context, p *P2P) error {
+	p.restartMtx.Lock()
+	gossipOptions := p.gossipOptions
+	p.restartMtx.Unlock()
+
+	err := p.restart(ctx, gossipOptions)
+	if err != nil {
+		log.WithError(err).Error("Failed to restart p2p, exiting")
+		return recoverExit(ctx, p)
+	}
+	return nil
+}
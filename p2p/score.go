@@ -0,0 +1,56 @@
+package p2p
+
+import (
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const scoreInspectPeriod = 10 * time.Second
+
+// peerScoreOptions enable gossipsub peer scoring for topics.
+// Only behaviour penalties and mesh participation are scored,
+// IP colocation is not used since onion peers share the tor address.
+func peerScoreOptions(topics []string, inspect pubsub.PeerScoreInspectFn) []pubsub.Option {
+	topicParams := make(map[string]*pubsub.TopicScoreParams)
+	for _, topic := range topics {
+		topicParams[topic] = &pubsub.TopicScoreParams{
+			SkipAtomicValidation:         true,
+			TopicWeight:                  1,
+			TimeInMeshWeight:             0.01,
+			TimeInMeshQuantum:            time.Second,
+			TimeInMeshCap:                3600,
+			FirstMessageDeliveriesWeight: 1,
+			FirstMessageDeliveriesDecay:  pubsub.ScoreParameterDecay(time.Hour),
+			FirstMessageDeliveriesCap:    100,
+		}
+	}
+
+	params := &pubsub.PeerScoreParams{
+		SkipAtomicValidation: true,
+		Topics:               topicParams,
+		AppSpecificScore: func(p peer.ID) float64 {
+			return 0
+		},
+		BehaviourPenaltyWeight:    -10,
+		BehaviourPenaltyThreshold: 6,
+		BehaviourPenaltyDecay:     pubsub.ScoreParameterDecay(10 * time.Minute),
+		DecayInterval:             pubsub.DefaultDecayInterval,
+		DecayToZero:               pubsub.DefaultDecayToZero,
+		RetainScore:               time.Hour,
+	}
+
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:             -500,
+		PublishThreshold:            -1000,
+		GraylistThreshold:           -2500,
+		AcceptPXThreshold:           0,
+		OpportunisticGraftThreshold: 0,
+	}
+
+	return []pubsub.Option{
+		pubsub.WithPeerScore(params, thresholds),
+		pubsub.WithPeerScoreInspect(inspect, scoreInspectPeriod),
+	}
+}
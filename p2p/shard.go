@@ -0,0 +1,90 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.samourai.io/wallet/samourai-soroban/internal/common"
+)
+
+const (
+	// ShardMissForward query peers subscribed to the key shard
+	ShardMissForward = "forward"
+	// ShardMissLocal only use the local directory
+	ShardMissLocal = "local"
+)
+
+// Sharding split directory writes in topics by hash buckets of common.KeyHash
+type Sharding struct {
+	Count      int
+	Miss       string
+	subscribed map[int]bool
+}
+
+// NewSharding create sharding from shard count and comma separated list of subscribed shards.
+// All shards are subscribed if subscribe is empty.
+func NewSharding(count int, subscribe, miss string) (Sharding, error) {
+	if count < 0 {
+		return Sharding{}, errors.New("invalid shard count")
+	}
+	switch miss {
+	case "":
+		miss = ShardMissForward
+	case ShardMissForward, ShardMissLocal:
+	default:
+		return Sharding{}, fmt.Errorf("invalid shard miss policy: %s", miss)
+	}
+
+	result := Sharding{
+		Count:      count,
+		Miss:       miss,
+		subscribed: make(map[int]bool),
+	}
+	for _, tok := range strings.Split(subscribe, ",") {
+		tok = strings.TrimSpace(tok)
+		if len(tok) == 0 {
+			continue
+		}
+		index, err := strconv.Atoi(tok)
+		if err != nil || index < 0 || index >= count {
+			return Sharding{}, fmt.Errorf("invalid shard index: %s", tok)
+		}
+		result.subscribed[index] = true
+	}
+	return result, nil
+}
+
+// Enabled return true if writes are published to shard topics
+func (p Sharding) Enabled() bool {
+	return p.Count > 1
+}
+
+// Index return shard index of key
+func (p Sharding) Index(domain, key string) int {
+	if !p.Enabled() {
+		return 0
+	}
+	hash := strings.TrimPrefix(common.KeyHash(domain, key), "k:")
+	data, err := hex.DecodeString(hash[:8])
+	if err != nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(data) % uint32(p.Count))
+}
+
+// Subscribed return true if shard index is subscribed
+func (p Sharding) Subscribed(index int) bool {
+	if !p.Enabled() || len(p.subscribed) == 0 {
+		return true
+	}
+	return p.subscribed[index]
+}
+
+// ShardTopic return topic name for shard index
+func ShardTopic(room string, index int) string {
+	return fmt.Sprintf("%s.shard-%d", room, index)
+}
@@ -2,16 +2,13 @@ package p2p
 
 import (
 	"context"
-	"crypto/ed25519"
-	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"sync"
+	"sync/atomic"
 
-	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
-	rand2 "math/rand/v2"
-	"os"
 	"strings"
 	"time"
 
@@ -19,10 +16,12 @@ import (
 	"github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
 	"github.com/libp2p/go-libp2p/core/discovery"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
 	"github.com/libp2p/go-libp2p/p2p/net/swarm"
@@ -31,20 +30,96 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// EnvelopeProtocolID is advertised by peers supporting envelope messages
+const EnvelopeProtocolID = protocol.ID("/soroban/envelope/1.0.0")
+
 // P2P for distributed soroban
 type P2P struct {
 	OnMessage chan Message
+	OnQuery   QueryHandler
 	ChildID   int
-	topic     *pubsub.Topic
-	host      host.Host
-	dht       *dht.IpfsDHT
+	Domain    string
+	Sharding  Sharding
+	Tor       soroban.TorInfo
+
+	// started node, replaced in one step by Start and Stop
+	current atomic.Pointer[node]
+	// restartMtx serialize Start, Stop and restarts from reload and recovery
+	restartMtx    sync.Mutex
+	options       soroban.P2PInfo
+	gossipOptions soroban.GossipInfo
+
+	statusMtx     sync.RWMutex
+	nodes         map[string]NodeHeartbeat
+	scores        map[peer.ID]float64
+	lastHeartbeat time.Time
+	connManager   ConnManagerStatus
+	peerstore     PeerstoreStatus
+
+	peerStatsMtx sync.Mutex
+	peerStats    map[peer.ID]*peerStat
+}
+
+// node is the started p2p subsystem, request handlers use a snapshot of the current node
+type node struct {
+	host           host.Host
+	dht            *dht.IpfsDHT
+	topic          *pubsub.Topic
+	shards         []*pubsub.Topic
+	tracer         *meshTracer
+	tor            *torService
+	cancel         context.CancelFunc
+	bootstrapPeers []multiaddr.Multiaddr
+	messageFormat  string
+	compression    string
+}
+
+// close node host, DHT and tor service, onion service is deleted before the host is closed
+func (n *node) close() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	if n.dht != nil {
+		if err := n.dht.Close(); err != nil {
+			log.WithError(err).Warning("Failed to close DHT")
+		}
+	}
+	if n.tor != nil {
+		n.tor.closeOnion()
+	}
+	if n.host != nil {
+		if err := n.host.Close(); err != nil {
+			log.WithError(err).Warning("Failed to close p2p host")
+		}
+	}
+	if n.tor != nil {
+		n.tor.closeClient()
+	}
+}
+
+// started return the current node, nil if p2p is not started
+func (p *P2P) started() *node {
+	return p.current.Load()
 }
 
 func (p *P2P) Valid() bool {
-	return p.topic != nil
+	n := p.started()
+	return n != nil && n.topic != nil
 }
 
 func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGossip soroban.GossipInfo, ready chan struct{}) error {
+	defer func() {
+		ready <- struct{}{}
+	}()
+
+	p.restartMtx.Lock()
+	defer p.restartMtx.Unlock()
+
+	return p.start(ctx, optionsP2P, optionsGossip)
+}
+
+// start must be called with restartMtx locked, node is only set when fully started
+func (p *P2P) start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGossip soroban.GossipInfo) (err error) {
 	p2pSeed := optionsP2P.Seed
 	listenPort := optionsP2P.ListenPort
 	lowWater := optionsP2P.LowWater
@@ -62,56 +137,94 @@ func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGoss
 	prunePeers := optionsGossip.PrunePeers
 	limit := optionsGossip.Limit
 
-	ctx = network.WithDialPeerTimeout(ctx, 3*time.Minute)
+	p.options = optionsP2P
+	p.gossipOptions = optionsGossip
+
+	n := &node{
+		messageFormat: optionsP2P.MessageFormat,
+		compression:   optionsP2P.Compression,
+	}
 	defer func() {
-		ready <- struct{}{}
+		if err != nil {
+			n.close()
+		}
 	}()
 
+	// p2p subsystem can be stopped independently
+	ctx, n.cancel = context.WithCancel(ctx)
+
+	p.statusMtx.Lock()
+	p.connManager = ConnManagerStatus{
+		LowWater:  lowWater,
+		HighWater: highWater,
+	}
+	p.peerstore = PeerstoreStatus{
+		Enabled: optionsP2P.PeerstoreFile != "-",
+	}
+	if p.peerstore.Enabled {
+		p.peerstore.File = peerstoreFile(optionsP2P.PeerstoreFile, p.ChildID)
+	}
+	p.statusMtx.Unlock()
+
+	ctx = network.WithDialPeerTimeout(ctx, 3*time.Minute)
+
 	mgr, err := connmgr.NewConnManager(lowWater, highWater)
 	if err != nil {
 		return err
 	}
 
-	// Generates a p2p seed automatically if none has been provided
-	if len(p2pSeed) == 0 {
-		_, pri, err := ed25519.GenerateKey(rand.Reader)
-		if err != nil {
-			log.Fatal(err)
-		}
-		p2pSeed = hex.EncodeToString(pri.Seed())
-	}
-
+	// p2p seed is generated automatically if none has been provided
 	var opts []libp2p.Option
-	p2pOpts, err := initTorP2P(ctx, p2pSeed, mgr, listenPort)
+	p2pOpts, service, err := initP2PTransport(ctx, p.Tor, fmt.Sprintf("p2p-c%d", p.ChildID), optionsP2P.Transport, p2pSeed, optionsP2P.Hostname, mgr, listenPort)
 	if err != nil {
 		return err
 	}
 	opts = append(opts, p2pOpts...)
+	n.tor = service
 
 	// create the swarm
 	swarm.BackoffBase = 30 * time.Second
-	p.host, err = libp2p.New(opts...)
+	n.host, err = libp2p.New(opts...)
 	if err != nil {
 		return err
 	}
 
+	// track connection success for peerstore persistence
+	n.host.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			p.recordPeerConnected(conn.RemotePeer())
+		},
+	})
+
+	// advertise envelope support to peers with identify protocol
+	n.host.SetStreamHandler(EnvelopeProtocolID, func(s network.Stream) {
+		s.Close()
+	})
+
 	isBoostrapNode := false
 
 	localAddresses := []string{}
-	for _, a := range p.host.Addrs() {
+	for _, a := range n.host.Addrs() {
 		localAddresses = append(localAddresses, a.String())
-		log.Printf("Peer address: %s/p2p/%s", a.String(), p.host.ID().String())
+		log.Printf("Peer address: %s/p2p/%s", a.String(), n.host.ID().String())
 	}
 
 	bootstrapAddresses := []multiaddr.Multiaddr{}
 	bootstrapAddr := strings.Split(bootstrap, ",")
 	for _, b := range bootstrapAddr {
+		if len(b) == 0 {
+			continue
+		}
 		a, err := multiaddr.NewMultiaddr(b)
 		if err != nil {
 			return err
 		}
 		bootstrapAddresses = append(bootstrapAddresses, a)
 		log.Debugf("Bootstrap address: %s", a.String())
+		// bootstrap address may use a dns name with clearnet transports
+		if info, err := peer.AddrInfoFromP2pAddr(a); err == nil && info.ID == n.host.ID() {
+			isBoostrapNode = true
+		}
 		for _, l := range localAddresses {
 			if strings.HasPrefix(a.String(), l) {
 				isBoostrapNode = true
@@ -120,6 +233,8 @@ func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGoss
 		}
 	}
 
+	n.bootstrapPeers = bootstrapAddresses
+
 	mode := dht.ModeClient
 	if isBoostrapNode {
 		mode = dht.ModeServer
@@ -128,24 +243,24 @@ func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGoss
 	}
 
 	log.Debugf("isBootstrap: %t", isBoostrapNode)
-	log.Debugf("DHT mode: %s", mode)
+	log.Debugf("DHT mode: %v", mode)
 
 	// Connect node to a few peers persisted on disk
 	if !isBoostrapNode && optionsP2P.PeerstoreFile != "-" {
-		err = p.ConnectToPersistedPeers(ctx, optionsP2P)
+		err = p.connectToPersistedPeers(ctx, n.host, optionsP2P)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Initialize and bootstrap the DHT
-	p.dht, err = NewDHT(ctx, p.host, mode, bootstrapAddresses...)
+	n.dht, err = NewDHT(ctx, n.host, mode, bootstrapAddresses...)
 	if err != nil {
 		return err
 	}
 
 	// Initialize the routing discovery for the pubsub protocol
-	routingDiscovery := drouting.NewRoutingDiscovery(p.dht)
+	routingDiscovery := drouting.NewRoutingDiscovery(n.dht)
 	discOpts := []discovery.Option{discovery.Limit(limit), discovery.TTL(30 * time.Second)}
 
 	// Initialize the gossipsub protocol
@@ -159,11 +274,27 @@ func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGoss
 	params.GossipFactor = 0.25
 	params.PrunePeers = prunePeers
 
-	gossipSub, err := pubsub.NewGossipSub(
-		ctx,
-		p.host,
+	n.tracer = newMeshTracer()
+	pubsubOpts := []pubsub.Option{
 		pubsub.WithGossipSubParams(params),
 		pubsub.WithDiscovery(routingDiscovery, pubsub.WithDiscoveryOpts(discOpts...)),
+		pubsub.WithMessageIdFn(messageID),
+		pubsub.WithRawTracer(n.tracer),
+	}
+	if optionsGossip.PeerScore {
+		topics := []string{room}
+		if p.Sharding.Enabled() {
+			for i := 0; i < p.Sharding.Count; i++ {
+				topics = append(topics, ShardTopic(room, i))
+			}
+		}
+		pubsubOpts = append(pubsubOpts, peerScoreOptions(topics, p.recordScores)...)
+	}
+
+	gossipSub, err := pubsub.NewGossipSub(
+		ctx,
+		n.host,
+		pubsubOpts...,
 	)
 	if err != nil {
 		return err
@@ -174,7 +305,7 @@ func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGoss
 		return err
 	}
 
-	p.topic = topic
+	n.topic = topic
 
 	// subscribe to topic
 	subscriber, err := topic.Subscribe()
@@ -182,20 +313,113 @@ func (p *P2P) Start(ctx context.Context, optionsP2P soroban.P2PInfo, optionsGoss
 		return err
 	}
 
-	go p.subscribe(ctx, subscriber)
+	go p.subscribe(ctx, n.host.ID(), subscriber)
+
+	// join shard topics, subscribe only to configured shards
+	if p.Sharding.Enabled() {
+		for i := 0; i < p.Sharding.Count; i++ {
+			shardTopic, err := gossipSub.Join(ShardTopic(room, i))
+			if err != nil {
+				return err
+			}
+			n.shards = append(n.shards, shardTopic)
+
+			if !p.Sharding.Subscribed(i) {
+				continue
+			}
+			shardSubscriber, err := shardTopic.Subscribe()
+			if err != nil {
+				return err
+			}
+			log.WithField("Shard", i).Debug("Subscribed to shard")
+
+			go p.subscribe(ctx, n.host.ID(), shardSubscriber)
+		}
+	}
+
+	n.host.SetStreamHandler(QueryProtocolID, func(s network.Stream) {
+		p.handleQuery(ctx, s)
+	})
+
+	// Discover peers on local network
+	if optionsP2P.MDNS {
+		err := startMDNS(ctx, n.host, room)
+		if err != nil {
+			// non fatal error, onion only nodes can't be announced with mDNS
+			log.WithError(err).Warning("Failed to start mDNS discovery")
+		}
+	}
 
 	// Start persisting the peerstore
 	if optionsP2P.PeerstoreFile != "-" {
 		go StartPeerstorePersistence(ctx, optionsP2P, p)
 	}
 
+	p.current.Store(n)
 	return nil
 }
 
+// Stop the p2p subsystem, OnMessage channel is kept for a later Start
+func (p *P2P) Stop() {
+	p.restartMtx.Lock()
+	defer p.restartMtx.Unlock()
+
+	p.stop()
+}
+
+// stop must be called with restartMtx locked
+func (p *P2P) stop() {
+	n := p.current.Swap(nil)
+	if n != nil {
+		n.close()
+	}
+}
+
+// restart the p2p subsystem with gossip options, previous gossip options are restored if start fails.
+// Restarts from reload and recovery are serialized.
+func (p *P2P) restart(ctx context.Context, optionsGossip soroban.GossipInfo) error {
+	p.restartMtx.Lock()
+	defer p.restartMtx.Unlock()
+
+	if p.started() == nil {
+		return errors.New("p2p not started")
+	}
+	previous := p.gossipOptions
+
+	p.stop()
+	err := p.start(ctx, p.options, optionsGossip)
+	if err == nil || previous == optionsGossip {
+		return err
+	}
+
+	log.WithError(err).Error("Failed to restart p2p, restoring previous gossip options")
+	if errRestore := p.start(ctx, p.options, previous); errRestore != nil {
+		return errRestore
+	}
+	return err
+}
+
+// ReloadGossip restart the p2p subsystem when gossip options changed
+func (p *P2P) ReloadGossip(ctx context.Context, optionsGossip soroban.GossipInfo) error {
+	p.restartMtx.Lock()
+	unchanged := p.gossipOptions == optionsGossip
+	p.restartMtx.Unlock()
+	if p.started() == nil || unchanged {
+		return nil
+	}
+
+	log.Info("Restarting p2p with new gossip options")
+	return p.restart(ctx, optionsGossip)
+}
+
 // start subsriber to topic
-func (p *P2P) subscribe(ctx context.Context, subscriber *pubsub.Subscription) {
+func (p *P2P) subscribe(ctx context.Context, self peer.ID, subscriber *pubsub.Subscription) {
 	for {
 		msg, err := subscriber.Next(ctx)
+		if ctx.Err() != nil {
+			subscriber.Cancel()
+			return
+		}
 		if err != nil {
 			log.Printf("failed to get next message")
 			<-time.After(time.Second)
@@ -203,7 +427,7 @@ func (p *P2P) subscribe(ctx context.Context, subscriber *pubsub.Subscription) {
 		}
 
 		// only consider messages delivered by other peers
-		if msg.ReceivedFrom == p.host.ID() {
+		if msg.ReceivedFrom == self {
 			continue
 		}
 
@@ -213,22 +437,46 @@ func (p *P2P) subscribe(ctx context.Context, subscriber *pubsub.Subscription) {
 			continue
 		}
 
+		// origin must be the author authenticated by pubsub message signature
+		from := msg.GetFrom()
+		if len(from) == 0 || (len(message.Origin) > 0 && message.Origin != from.String()) {
+			log.WithField("Origin", message.Origin).WithField("From", from).Debug("Skip message with invalid origin")
+			continue
+		}
+		message.Origin = from.String()
+
 		p.OnMessage <- message
 	}
 }
 
 // Publish to topic
 func (p *P2P) Publish(ctx context.Context, msg string) error {
+	n := p.started()
+	if n == nil {
+		return nil
+	}
+	return publishTopic(ctx, n.topic, msg)
+}
+
+func publishTopic(ctx context.Context, topic *pubsub.Topic, msg string) error {
 	if len(msg) == 0 {
 		return errors.New("failed to publish empty message")
 	}
-	if p.topic == nil {
+	if topic == nil {
 		return nil
 	}
-	p.topic.Publish(ctx, []byte(msg))
+	topic.Publish(ctx, []byte(msg))
 	return nil
 }
 
+// shardTopic return node topic for key, room topic is used when sharding is disabled
+func (p *P2P) shardTopic(n *node, key string) *pubsub.Topic {
+	if !p.Sharding.Enabled() || len(n.shards) != p.Sharding.Count {
+		return n.topic
+	}
+	return n.shards[p.Sharding.Index(p.Domain, key)]
+}
+
 // Publish to topic
 func (p *P2P) PublishJson(ctx context.Context, context string, payload interface{}) error {
 	message, err := NewMessage(context, payload)
@@ -236,92 +484,102 @@ func (p *P2P) PublishJson(ctx context.Context, context string, payload interface
 		return err
 	}
 
-	data, err := message.ToBytes()
+	return p.PublishMessage(ctx, message)
+}
+
+// PublishJsonToShard publish to key shard topic
+func (p *P2P) PublishJsonToShard(ctx context.Context, key, context string, payload interface{}) error {
+	message, err := NewMessage(context, payload)
 	if err != nil {
 		return err
 	}
 
-	return p.Publish(ctx, string(data))
+	return p.PublishMessageToShard(ctx, key, message)
 }
 
-// Persist the Peerstore
-func StartPeerstorePersistence(ctx context.Context, optionsP2P soroban.P2PInfo, p *P2P) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info("Exiting peerstore persistence Loop")
-			return
-		case <-ticker.C:
-			p.PersistPeerstore(ctx, optionsP2P)
-		}
+// PublishMessage to topic, message ID is kept if already set
+func (p *P2P) PublishMessage(ctx context.Context, message Message) error {
+	n := p.started()
+	if n == nil {
+		return nil
 	}
+	data, err := p.encodeMessage(n, n.topic, message)
+	if err != nil {
+		return err
+	}
+
+	return publishTopic(ctx, n.topic, string(data))
 }
 
-func (p *P2P) PersistPeerstore(ctx context.Context, optionsP2P soroban.P2PInfo) error {
-	var peersAddrs []peer.AddrInfo
-	for _, peerId := range p.host.Network().Peerstore().PeersWithAddrs() {
-		if p.host.ID() == peerId {
-			continue
-		}
-		peerInfo := p.host.Network().Peerstore().PeerInfo(peerId)
-		if len(peerInfo.Addrs) > 0 {
-			peersAddrs = append(peersAddrs, peerInfo)
-		}
+// PublishMessageToShard publish to key shard topic, message ID is kept if already set
+func (p *P2P) PublishMessageToShard(ctx context.Context, key string, message Message) error {
+	n := p.started()
+	if n == nil {
+		return nil
 	}
-
-	if len(peersAddrs) > 0 {
-		bytes, _ := json.Marshal(peersAddrs)
-		filepath := fmt.Sprintf(optionsP2P.PeerstoreFile+".c%d.json", p.ChildID)
-		file, err := os.Create(filepath)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		file.Write(bytes)
-		file.Write([]byte("\n"))
+	topic := p.shardTopic(n, key)
+	data, err := p.encodeMessage(n, topic, message)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return publishTopic(ctx, topic, string(data))
 }
 
-func (p *P2P) ConnectToPersistedPeers(ctx context.Context, optionsP2P soroban.P2PInfo) error {
-	filepath := fmt.Sprintf(optionsP2P.PeerstoreFile+".c%d.json", p.ChildID)
-	bytes, err := os.ReadFile(filepath)
-	if err != nil {
-		return nil
+// encodeMessage with format supported by topic peers
+func (p *P2P) encodeMessage(n *node, topic *pubsub.Topic, message Message) ([]byte, error) {
+	if len(message.ID) == 0 {
+		message.ID = newMessageID()
+	}
+	if message.Timestamp == 0 {
+		message.Timestamp = time.Now().UnixNano()
+	}
+	if len(message.Origin) == 0 {
+		message.Origin = n.host.ID().String()
 	}
 
-	var peersAddrs []peer.AddrInfo
-	err = json.Unmarshal(bytes, &peersAddrs)
-	if err != nil {
-		return err
+	return message.Encode(n.publishFormat(topic), n.compression)
+}
+
+// publishFormat return message format used for publishing to topic.
+// In auto mode, envelope is used only when topic has peers and all of them advertise envelope support.
+func (n *node) publishFormat(topic *pubsub.Topic) string {
+	switch n.messageFormat {
+	case MessageFormatLegacy, MessageFormatEnvelope:
+		return n.messageFormat
 	}
 
-	maxNbSelectedPeers := optionsP2P.LowWater / 2
-	if len(peersAddrs) > maxNbSelectedPeers {
-		rand2.Shuffle(len(peersAddrs), func(i, j int) { peersAddrs[i], peersAddrs[j] = peersAddrs[j], peersAddrs[i] })
-		peersAddrs = peersAddrs[:maxNbSelectedPeers+1]
-	}
-
-	if len(peersAddrs) > 0 {
-		var wg sync.WaitGroup
-		for _, peerinfo := range peersAddrs {
-			if p.host.ID() != peerinfo.ID {
-				wg.Add(1)
-				go func(peerinfo peer.AddrInfo) {
-					log.Debugf("Boostrapping attempt with %v", peerinfo)
-					defer wg.Done()
-					if err := p.host.Connect(ctx, peerinfo); err != nil {
-						log.WithError(err).Warnf("Bootstrap warning: %v", peerinfo)
-					}
-				}(peerinfo)
-			}
+	if topic == nil || n.host == nil {
+		return MessageFormatLegacy
+	}
+	peers := topic.ListPeers()
+	if len(peers) == 0 {
+		return MessageFormatLegacy
+	}
+	for _, peerID := range peers {
+		supported, err := n.host.Peerstore().SupportsProtocols(peerID, EnvelopeProtocolID)
+		if err != nil || len(supported) == 0 {
+			return MessageFormatLegacy
 		}
-		wg.Wait()
 	}
+	return MessageFormatEnvelope
+}
 
-	return nil
+// messageID deduplicate on the decoded message ID, context and payload.
+// The same message is deduplicated whatever its format and compression,
+// and peers can't suppress other messages by reusing their ID.
+// Default pubsub ID is used for messages without ID.
+func messageID(pmsg *pb.Message) string {
+	message, err := MessageFromBytes(pmsg.GetData())
+	if err != nil || len(message.ID) == 0 {
+		return pubsub.DefaultMsgIdFn(pmsg)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(message.ID))
+	hash.Write([]byte{0})
+	hash.Write([]byte(message.Context))
+	hash.Write([]byte{0})
+	hash.Write(message.Payload)
+	return hex.EncodeToString(hash.Sum(nil))
 }
@@ -0,0 +1,193 @@
+package p2p
+
+import (
+	"sort"
+	"time"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// PeerStatus for connected peer
+type PeerStatus struct {
+	ID        string   `json:"id"`
+	Addrs     []string `json:"addrs"`
+	Direction string   `json:"direction"`
+	LatencyMs float64  `json:"latency_ms"`
+	Score     *float64 `json:"score,omitempty"`
+}
+
+// TopicStatus for joined topic
+type TopicStatus struct {
+	Name       string   `json:"name"`
+	Subscribed bool     `json:"subscribed"`
+	Peers      int      `json:"peers"`
+	Mesh       []string `json:"mesh"`
+}
+
+// DHTStatus for kademlia DHT
+type DHTStatus struct {
+	Mode             string `json:"mode"`
+	RoutingTableSize int    `json:"routing_table_size"`
+}
+
+// ConnManagerStatus for connection manager
+type ConnManagerStatus struct {
+	LowWater    int `json:"low_water"`
+	HighWater   int `json:"high_water"`
+	Connections int `json:"connections"`
+}
+
+// PeerstoreStatus for peerstore persistence
+type PeerstoreStatus struct {
+	File          string     `json:"file"`
+	Enabled       bool       `json:"enabled"`
+	LastPersisted *time.Time `json:"last_persisted,omitempty"`
+	Peers         int        `json:"peers"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// Status of p2p node
+type Status struct {
+	Started       bool              `json:"started"`
+	ChildID       int               `json:"child_id"`
+	ID            string            `json:"id,omitempty"`
+	Addrs         []string          `json:"addrs,omitempty"`
+	Peers         []PeerStatus      `json:"peers,omitempty"`
+	Topics        []TopicStatus     `json:"topics,omitempty"`
+	DHT           DHTStatus         `json:"dht"`
+	ConnManager   ConnManagerStatus `json:"conn_manager"`
+	LastHeartbeat *time.Time        `json:"last_heartbeat,omitempty"`
+	Nodes         []NodeHeartbeat   `json:"nodes,omitempty"`
+	Peerstore     PeerstoreStatus   `json:"peerstore"`
+}
+
+// RecordHeartbeat store last heartbeat received time
+func (p *P2P) RecordHeartbeat() {
+	p.statusMtx.Lock()
+	defer p.statusMtx.Unlock()
+
+	p.lastHeartbeat = time.Now().UTC()
+}
+
+func (p *P2P) recordScores(scores map[peer.ID]float64) {
+	p.statusMtx.Lock()
+	defer p.statusMtx.Unlock()
+
+	p.scores = scores
+}
+
+func (p *P2P) recordPeerstore(peers int, err error) {
+	p.statusMtx.Lock()
+	defer p.statusMtx.Unlock()
+
+	now := time.Now().UTC()
+	p.peerstore.LastPersisted = &now
+	p.peerstore.Peers = peers
+	p.peerstore.Error = ""
+	if err != nil {
+		p.peerstore.Error = err.Error()
+	}
+}
+
+// Status return p2p host, gossip and DHT state
+func (p *P2P) Status() Status {
+	// node snapshot, p2p can be restarted concurrently
+	n := p.started()
+
+	p.statusMtx.RLock()
+	defer p.statusMtx.RUnlock()
+
+	result := Status{
+		ChildID:     p.ChildID,
+		ConnManager: p.connManager,
+		Peerstore:   p.peerstore,
+	}
+	if !p.lastHeartbeat.IsZero() {
+		lastHeartbeat := p.lastHeartbeat
+		result.LastHeartbeat = &lastHeartbeat
+	}
+	for _, node := range p.nodes {
+		result.Nodes = append(result.Nodes, node)
+	}
+	sort.Slice(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].Origin < result.Nodes[j].Origin
+	})
+	if n == nil {
+		return result
+	}
+
+	result.Started = true
+	result.ID = n.host.ID().String()
+	for _, addr := range n.host.Addrs() {
+		result.Addrs = append(result.Addrs, addr.String())
+	}
+
+	peerstore := n.host.Peerstore()
+	conns := n.host.Network().Conns()
+	result.ConnManager.Connections = len(conns)
+	for _, conn := range conns {
+		peerID := conn.RemotePeer()
+		status := PeerStatus{
+			ID:        peerID.String(),
+			Addrs:     []string{conn.RemoteMultiaddr().String()},
+			Direction: conn.Stat().Direction.String(),
+			LatencyMs: float64(peerstore.LatencyEWMA(peerID).Microseconds()) / 1000,
+		}
+		if score, ok := p.scores[peerID]; ok {
+			status.Score = &score
+		}
+		result.Peers = append(result.Peers, status)
+	}
+	sort.Slice(result.Peers, func(i, j int) bool {
+		return result.Peers[i].ID < result.Peers[j].ID
+	})
+
+	if n.topic != nil {
+		result.Topics = append(result.Topics, n.topicStatus(n.topic.String(), len(n.topic.ListPeers()), true))
+	}
+	for i, topic := range n.shards {
+		result.Topics = append(result.Topics, n.topicStatus(topic.String(), len(topic.ListPeers()), p.Sharding.Subscribed(i)))
+	}
+
+	if n.dht != nil {
+		result.DHT = DHTStatus{
+			Mode:             dhtMode(n.dht.Mode()),
+			RoutingTableSize: n.dht.RoutingTable().Size(),
+		}
+	}
+
+	return result
+}
+
+func (n *node) topicStatus(name string, peers int, subscribed bool) TopicStatus {
+	result := TopicStatus{
+		Name:       name,
+		Subscribed: subscribed,
+		Peers:      peers,
+		Mesh:       make([]string, 0),
+	}
+	if n.tracer == nil {
+		return result
+	}
+	for _, peerID := range n.tracer.Mesh(name) {
+		result.Mesh = append(result.Mesh, peerID.String())
+	}
+	sort.Strings(result.Mesh)
+	return result
+}
+
+func dhtMode(mode dht.ModeOpt) string {
+	switch mode {
+	case dht.ModeClient:
+		return "client"
+	case dht.ModeServer:
+		return "server"
+	case dht.ModeAuto:
+		return "auto"
+	case dht.ModeAutoServer:
+		return "auto-server"
+	default:
+		return "unknown"
+	}
+}
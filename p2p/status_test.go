@@ -0,0 +1,29 @@
+package p2p
+
+import (
+	"sync"
+	"testing"
+)
+
+func Test_StatusStopped(t *testing.T) {
+	p := &P2P{ChildID: 1}
+	p.RecordNodeHeartbeat("origin", Heartbeat{Version: "test"})
+
+	// status is read while p2p is stopped by recovery or reload
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.Stop()
+		}()
+		go func() {
+			defer wg.Done()
+			status := p.Status()
+			if status.Started || status.ChildID != 1 || len(status.Nodes) != 1 {
+				t.Errorf("Status() = %+v", status)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -3,8 +3,7 @@ package p2p
 import (
 	"context"
 	"crypto/ed25519"
-	"crypto/rand"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 
@@ -14,67 +13,95 @@ import (
 	"github.com/cretz/bine/tor"
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/crypto"
-	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
 	"github.com/multiformats/go-multiaddr"
 	madns "github.com/multiformats/go-multiaddr-dns"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func initTorP2P(ctx context.Context, p2pSeed string, mgr *connmgr.BasicConnMgr, listenPort int) ([]libp2p.Option, error) {
-	extraArgs := []string{
-		"--DNSPort", "2121",
+// torService is the p2p onion service and the embedded tor running it
+type torService struct {
+	ctx    context.Context
+	onion  *tor.OnionService
+	client *tor.Tor // nil with external tor, shared connection must not be closed
+}
+
+// closeOnion delete the onion service, it must be closed before a restart re-adds the same key
+func (t *torService) closeOnion() {
+	if t.onion == nil {
+		return
 	}
+	if err := t.onion.Close(); err != nil {
+		log.WithError(err).Warning("Failed to close p2p onion service")
+	}
+	t.onion = nil
+}
 
-	var privateKey ed25519.PrivateKey
-	var priv crypto.PrivKey
-	if len(p2pSeed) > 0 {
-		if p2pSeed == "auto" {
-			_, pri, err := ed25519.GenerateKey(rand.Reader)
-			if err != nil {
-				return nil, err
-			}
+// closeClient stop embedded tor and remove it from tor clients
+func (t *torService) closeClient() {
+	if t.client == nil {
+		return
+	}
+	if err := t.client.Close(); err != nil {
+		log.WithError(err).Warning("Failed to close p2p tor client")
+	}
+	soroban.RemoveTorClient(t.ctx, t.client)
+	t.client = nil
+}
 
-			p2pSeed = hex.EncodeToString(pri.Seed())
-		}
-		data, err := hex.DecodeString(p2pSeed)
-		if err != nil {
-			return nil, err
-		}
-		priv, err = crypto.UnmarshalSecp256k1PrivateKey(data)
-		if err != nil {
-			return nil, err
-		}
-		privateKey = ed25519.NewKeyFromSeed(data)
+// initTorP2P start embedded tor, or use external tor, and return onion transport options.
+// Onion service local port is automatically chosen when localPort is 0.
+// Returned tor service must be closed with the p2p host.
+func initTorP2P(ctx context.Context, torOptions soroban.TorInfo, instance string, priv crypto.PrivKey, privateKey ed25519.PrivateKey, listenPort, localPort int) (_ []libp2p.Option, _ *torService, err error) {
+	dnsAddress := torOptions.DNSAddress
+	if torOptions.External() && len(dnsAddress) == 0 {
+		return nil, nil, errors.New("tor DNS address is required with external tor")
+	}
+	if len(dnsAddress) == 0 {
+		dnsAddress = "localhost:2121"
 	}
 
-	// Create the embedded Tor client.
-	torClient, err := tor.Start(ctx, &tor.StartConf{
+	extraArgs := []string{
+		"--DNSPort", "2121",
+	}
+
+	// Create the embedded Tor client, or connect to external tor.
+	torClient, err := soroban.StartTor(ctx, torOptions, instance, &tor.StartConf{
 		DebugWriter:       io.Discard,
 		TempDataDirBase:   "/tmp",
 		RetainTempDataDir: false,
 		ExtraArgs:         extraArgs,
 	})
 	if err != nil {
-		log.WithError(err).Error("Failed to tor.Start")
-		return nil, err
+		log.WithError(err).Error("Failed to start p2p tor")
+		return nil, nil, err
+	}
+	service := &torService{
+		ctx:    ctx,
+		client: embeddedTor(torOptions, torClient),
+	}
+	defer func() {
+		if err != nil {
+			service.closeOnion()
+			service.closeClient()
+		}
+	}()
+	if !torOptions.External() {
+		// wait for network ready
+		log.Info("Waiting for p2p tor network")
+		torClient.EnableNetwork(ctx, true)
 	}
-	torClient.DeleteDataDirOnClose = true
-	// wait for network ready
-	log.Info("Waiting for p2p tor network")
-	soroban.AddTorClient(ctx, torClient)
-	torClient.EnableNetwork(ctx, true)
 
 	// Create the onion service.
-	onionService, err := torClient.Listen(ctx, &tor.ListenConf{
+	service.onion, err = torClient.Listen(ctx, &tor.ListenConf{
 		RemotePorts: []int{listenPort},
-		LocalPort:   listenPort,
+		LocalPort:   localPort,
 		Version3:    true,
 		Key:         privateKey,
 	})
 	if err != nil {
 		log.WithError(err).Error("Failed to torClient.Listen")
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Create the dialer.
@@ -82,10 +109,10 @@ func initTorP2P(ctx context.Context, p2pSeed string, mgr *connmgr.BasicConnMgr,
 	// IMPORTANT: If you are genuinely trying to anonymize your IP you will need to route
 	// any non-libp2p traffic through this dialer as well. For example, any HTTP requests
 	// you make MUST go through this dialer.
-	dialer, err := torClient.Dialer(ctx, nil)
+	dialer, err := soroban.TorDialer(ctx, torOptions, torClient)
 	if err != nil {
 		log.WithError(err).Error("Failed to torClient.Dialer")
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Override the default lip2p DNS resolver. We need this because libp2p address may contain a
@@ -94,23 +121,26 @@ func initTorP2P(ctx context.Context, p2pSeed string, mgr *connmgr.BasicConnMgr,
 	//
 	// Note you must enter the DNS resolver address that was used when creating the Tor client.
 	//resolver := madns.DefaultResolver // Noop
-	madns.DefaultResolver = onion.NewTorResolver("localhost:2121")
+	madns.DefaultResolver = onion.NewTorResolver(dnsAddress)
 
 	// Create the libp2p transport option.
 	// Create address option.
-	onionAddr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/onion3/%s:%d", onionService.ID, listenPort))
+	onionAddr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/onion3/%s:%d", service.onion.ID, listenPort))
 	if err != nil {
 		log.WithError(err).Error("Failed to NewMultiaddr onion3")
-		return nil, err
+		return nil, nil, err
 	}
 
 	return []libp2p.Option{
-		libp2p.Identity(priv),
 		libp2p.ListenAddrs(onionAddr),
-		libp2p.Transport(onion.NewOnionTransportC(priv, dialer, onionService)),
+		libp2p.Transport(onion.NewOnionTransportC(priv, dialer, service.onion)),
 		libp2p.DefaultMultiaddrResolver,
-		libp2p.ConnectionManager(mgr),
-		libp2p.Ping(true),
-		libp2p.UserAgent("Soroban"),
-	}, nil
+	}, service, nil
+}
+
+func embeddedTor(torOptions soroban.TorInfo, torClient *tor.Tor) *tor.Tor {
+	if torOptions.External() {
+		return nil
+	}
+	return torClient
 }
@@ -0,0 +1,145 @@
+package p2p
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+// fakeTorControl is a minimal tor control port registering onion services like tor
+type fakeTorControl struct {
+	listener net.Listener
+	mtx      sync.Mutex
+	onions   map[string]bool
+}
+
+func newFakeTorControl(t *testing.T) *fakeTorControl {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	control := &fakeTorControl{
+		listener: listener,
+		onions:   make(map[string]bool),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go control.serve(conn)
+		}
+	}()
+	return control
+}
+
+func (p *fakeTorControl) count() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.onions)
+}
+
+func (p *fakeTorControl) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var added string
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		reply := "250 OK\r\n"
+		p.mtx.Lock()
+		switch fields[0] {
+		case "PROTOCOLINFO":
+			reply = "250-PROTOCOLINFO 1\r\n250-AUTH METHODS=NULL\r\n250-VERSION Tor=\"0.4.8.0\"\r\n250 OK\r\n"
+		case "GETCONF":
+			reply = "250 DisableNetwork=0\r\n"
+		case "ADD_ONION":
+			hash := sha256.Sum256([]byte(fields[1]))
+			serviceID := strings.ToLower(base32.StdEncoding.EncodeToString(append(hash[:], 0, 0, 3)))
+			if p.onions[serviceID] {
+				reply = "550 Onion address collision\r\n"
+				break
+			}
+			p.onions[serviceID] = true
+			added = serviceID
+			reply = fmt.Sprintf("250-ServiceID=%s\r\n250 OK\r\n", serviceID)
+		case "DEL_ONION":
+			if !p.onions[fields[1]] {
+				reply = "552 Unknown Onion Service id\r\n"
+				break
+			}
+			delete(p.onions, fields[1])
+		case "SETEVENTS":
+			// onion service descriptor is published
+			if len(fields) > 1 && len(added) > 0 {
+				reply += fmt.Sprintf("650 HS_DESC UPLOADED %s UNKNOWN hsdir\r\n", added)
+				added = ""
+			}
+		}
+		p.mtx.Unlock()
+
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func Test_torServiceRestartExternal(t *testing.T) {
+	control := newFakeTorControl(t)
+	resolver := madns.DefaultResolver
+	t.Cleanup(func() { madns.DefaultResolver = resolver })
+
+	ctx := soroban.WithTorContext(context.Background())
+	defer soroban.Shutdown(ctx)
+
+	torOptions := soroban.TorInfo{
+		ControlAddress: control.listener.Addr().String(),
+		SocksAddress:   "127.0.0.1:9050",
+		DNSAddress:     "127.0.0.1:5353",
+	}
+	priv, privateKey, err := keysFromSeed("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// restart re-adds the same onion key to the shared tor
+	for i := 0; i < 2; i++ {
+		_, service, err := initTorP2P(ctx, torOptions, "p2p-c0", priv, privateKey, 1042, 0)
+		if err != nil {
+			t.Fatalf("initTorP2P() start %d = %v", i, err)
+		}
+		if service.client != nil {
+			t.Error("external tor must not be closed with node")
+		}
+		if control.count() != 1 {
+			t.Errorf("onion services = %d", control.count())
+		}
+
+		n := &node{tor: service}
+		n.close()
+		if control.count() != 0 {
+			t.Errorf("onion service not deleted on close")
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package p2p
+
+import (
+	"sync"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// meshTracer keep track of gossipsub mesh membership
+type meshTracer struct {
+	sync.RWMutex
+	mesh map[string]map[peer.ID]struct{}
+}
+
+func newMeshTracer() *meshTracer {
+	return &meshTracer{
+		mesh: make(map[string]map[peer.ID]struct{}),
+	}
+}
+
+// Mesh return mesh peers for topic
+func (t *meshTracer) Mesh(topic string) []peer.ID {
+	t.RLock()
+	defer t.RUnlock()
+
+	result := make([]peer.ID, 0, len(t.mesh[topic]))
+	for p := range t.mesh[topic] {
+		result = append(result, p)
+	}
+	return result
+}
+
+func (t *meshTracer) Join(topic string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if _, ok := t.mesh[topic]; !ok {
+		t.mesh[topic] = make(map[peer.ID]struct{})
+	}
+}
+
+func (t *meshTracer) Leave(topic string) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.mesh, topic)
+}
+
+func (t *meshTracer) Graft(p peer.ID, topic string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if _, ok := t.mesh[topic]; !ok {
+		t.mesh[topic] = make(map[peer.ID]struct{})
+	}
+	t.mesh[topic][p] = struct{}{}
+}
+
+func (t *meshTracer) Prune(p peer.ID, topic string) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.mesh[topic], p)
+}
+
+func (t *meshTracer) RemovePeer(p peer.ID) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, peers := range t.mesh {
+		delete(peers, p)
+	}
+}
+
+func (t *meshTracer) AddPeer(p peer.ID, proto protocol.ID)             {}
+func (t *meshTracer) ValidateMessage(msg *pubsub.Message)              {}
+func (t *meshTracer) DeliverMessage(msg *pubsub.Message)               {}
+func (t *meshTracer) RejectMessage(msg *pubsub.Message, reason string) {}
+func (t *meshTracer) DuplicateMessage(msg *pubsub.Message)             {}
+func (t *meshTracer) ThrottlePeer(p peer.ID)                           {}
+func (t *meshTracer) RecvRPC(rpc *pubsub.RPC)                          {}
+func (t *meshTracer) SendRPC(rpc *pubsub.RPC, p peer.ID)               {}
+func (t *meshTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)               {}
+func (t *meshTracer) UndeliverableMessage(msg *pubsub.Message)         {}
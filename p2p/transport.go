@@ -0,0 +1,108 @@
+package p2p
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
+	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
+	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+)
+
+const (
+	TransportTor    = "tor"
+	TransportTCP    = "tcp"
+	TransportQUIC   = "quic"
+	TransportTCPTor = "tcp+tor"
+)
+
+// initP2PTransport return libp2p host options for transport mode.
+// Tor service is returned for tor transports, tor instance name is used for its data directory.
+func initP2PTransport(ctx context.Context, torOptions soroban.TorInfo, torInstance, transport, p2pSeed, hostname string, mgr *connmgr.BasicConnMgr, listenPort int) ([]libp2p.Option, *torService, error) {
+	priv, privateKey, err := keysFromSeed(p2pSeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(hostname) == 0 {
+		hostname = "0.0.0.0"
+	}
+
+	opts := []libp2p.Option{
+		libp2p.Identity(priv),
+		libp2p.ConnectionManager(mgr),
+		libp2p.Ping(true),
+		libp2p.UserAgent("Soroban"),
+	}
+
+	var service *torService
+	switch transport {
+	case TransportTor, "":
+		var torOpts []libp2p.Option
+		torOpts, service, err = initTorP2P(ctx, torOptions, torInstance, priv, privateKey, listenPort, listenPort)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, torOpts...)
+
+	case TransportTCP:
+		opts = append(opts,
+			libp2p.ListenAddrStrings(fmt.Sprintf("/ip4/%s/tcp/%d", hostname, listenPort)),
+			libp2p.Transport(tcp.NewTCPTransport),
+		)
+
+	case TransportQUIC:
+		opts = append(opts,
+			libp2p.ListenAddrStrings(fmt.Sprintf("/ip4/%s/udp/%d/quic-v1", hostname, listenPort)),
+			libp2p.Transport(libp2pquic.NewTransport),
+		)
+
+	case TransportTCPTor:
+		// onion service use a random local port, listenPort is used by tcp transport
+		var torOpts []libp2p.Option
+		torOpts, service, err = initTorP2P(ctx, torOptions, torInstance, priv, privateKey, listenPort, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, torOpts...)
+		opts = append(opts,
+			libp2p.ListenAddrStrings(fmt.Sprintf("/ip4/%s/tcp/%d", hostname, listenPort)),
+			libp2p.Transport(tcp.NewTCPTransport),
+		)
+
+	default:
+		return nil, nil, fmt.Errorf("unknown p2p transport: %s", transport)
+	}
+
+	return opts, service, nil
+}
+
+// keysFromSeed return libp2p identity and onion key from hex seed
+func keysFromSeed(p2pSeed string) (crypto.PrivKey, ed25519.PrivateKey, error) {
+	if len(p2pSeed) == 0 || p2pSeed == "auto" {
+		_, pri, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		p2pSeed = hex.EncodeToString(pri.Seed())
+	}
+	data, err := hex.DecodeString(p2pSeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) != ed25519.SeedSize {
+		return nil, nil, errors.New("invalid p2p seed length")
+	}
+	priv, err := crypto.UnmarshalSecp256k1PrivateKey(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv, ed25519.NewKeyFromSeed(data), nil
+}
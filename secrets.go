@@ -0,0 +1,51 @@
+package soroban
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ChildSecrets are sent to child processes on a file descriptor (IPC.SecretsFd),
+// out of the process arguments and environment
+type ChildSecrets struct {
+	P2PSeed            string `json:",omitempty"`
+	TorControlPassword string `json:",omitempty"`
+}
+
+// Empty return true if there is no secret to send
+func (p ChildSecrets) Empty() bool {
+	return len(p.P2PSeed) == 0 && len(p.TorControlPassword) == 0
+}
+
+// ReadChildSecrets read json secrets from file descriptor, used by child processes
+func ReadChildSecrets(fd int) (ChildSecrets, error) {
+	file := os.NewFile(uintptr(fd), "secrets")
+	if file == nil {
+		return ChildSecrets{}, fmt.Errorf("invalid secrets file descriptor: %d", fd)
+	}
+	defer file.Close()
+
+	var result ChildSecrets
+	err := json.NewDecoder(io.LimitReader(file, 4096)).Decode(&result)
+	if err != nil {
+		return ChildSecrets{}, fmt.Errorf("invalid secrets: %w", err)
+	}
+	return result, nil
+}
+
+// WithChildSecrets return options with secrets read from IPC.SecretsFd, options are unchanged without secrets fd
+func (p Options) WithChildSecrets() (Options, error) {
+	if p.IPC.SecretsFd <= 0 {
+		return p, nil
+	}
+	secrets, err := ReadChildSecrets(p.IPC.SecretsFd)
+	if err != nil {
+		return p, err
+	}
+	p.IPC.SecretsFd = 0
+	p.P2P.Seed = secrets.P2PSeed
+	p.Tor.ControlPassword = secrets.TorControlPassword
+	return p, nil
+}
@@ -0,0 +1,33 @@
+package soroban
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func Test_OptionsWithChildSecrets(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(ChildSecrets{P2PSeed: "seed", TorControlPassword: "password"})
+	writer.Write(data)
+	writer.Close()
+
+	options := DefaultOptions
+	options.IPC.SecretsFd = int(reader.Fd())
+	options, err = options.WithChildSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.P2P.Seed != "seed" || options.Tor.ControlPassword != "password" || options.IPC.SecretsFd != 0 {
+		t.Errorf("WithChildSecrets() = %+v, %+v", options.P2P, options.Tor)
+	}
+
+	// options without secrets fd are unchanged
+	unchanged, err := DefaultOptions.WithChildSecrets()
+	if err != nil || unchanged.P2P.Seed != DefaultOptions.P2P.Seed {
+		t.Errorf("WithChildSecrets() = %v, %v", unchanged.P2P.Seed, err)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"code.samourai.io/wallet/samourai-soroban/internal"
+	"code.samourai.io/wallet/samourai-soroban/services"
+
+	"github.com/gorilla/rpc"
+	gjson "github.com/gorilla/rpc/json"
+	log "github.com/sirupsen/logrus"
+)
+
+// startAdminServer serve admin json-rpc service on localhost only
+func startAdminServer(ctx context.Context, port int) {
+	rpcServer := rpc.NewServer()
+	rpcServer.RegisterCodec(gjson.NewCodec(), "application/json")
+	rpcServer.RegisterCodec(gjson.NewCodec(), "application/json;charset=UTF-8")
+
+	err := rpcServer.RegisterService(new(services.Admin), "admin")
+	if err != nil {
+		log.WithError(err).Error("Failed to register admin service")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/rpc", rpcServer)
+
+	addr := fmt.Sprintf("localhost:%d", port)
+	adminServer := http.Server{
+		Addr: addr,
+		ConnContext: func(connCtx context.Context, c net.Conn) context.Context {
+			if directory := internal.DirectoryFromContext(ctx); directory != nil {
+				connCtx = context.WithValue(connCtx, internal.SorobanDirectoryKey, directory)
+			}
+			if p2P := internal.P2PFromContext(ctx); p2P != nil {
+				connCtx = context.WithValue(connCtx, internal.SorobanP2PKey, p2P)
+			}
+			return connCtx
+		},
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		adminServer.Close()
+	}()
+
+	log.WithField("Addr", addr).Info("Admin server started")
+	err = adminServer.ListenAndServe()
+	if err != http.ErrServerClosed {
+		log.WithError(err).Error("Admin Http Server exited")
+	}
+}
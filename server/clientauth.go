@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cretz/bine/control"
+	"github.com/cretz/bine/tor"
+	torEd25519 "github.com/cretz/bine/torutil/ed25519"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// prefix of x25519 keys in tor client authorization files
+const clientAuthKeyPrefix = "descriptor:x25519:"
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type ClientAuthEntry struct {
+	Name      string `yaml:"name"`
+	PublicKey string `yaml:"publickey"`
+}
+
+// ClientAuthConfig list x25519 public keys of clients authorized to connect to the onion service
+type ClientAuthConfig struct {
+	Clients []ClientAuthEntry `yaml:"clients"`
+}
+
+func ClientAuthLoad(filename string) (ClientAuthConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return ClientAuthConfig{}, err
+	}
+	var config ClientAuthConfig
+	err = yaml.Unmarshal(data, &config)
+	return config, err
+}
+
+// PublicKeys return base32 encoded public keys for ADD_ONION ClientAuthV3
+func (p ClientAuthConfig) PublicKeys() ([]string, error) {
+	var result []string
+	for _, client := range p.Clients {
+		key := strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(client.PublicKey), clientAuthKeyPrefix))
+		data, err := base32NoPadding.DecodeString(key)
+		if err != nil || len(data) != 32 {
+			return nil, fmt.Errorf("invalid client auth public key for %s", client.Name)
+		}
+		result = append(result, key)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no authorized clients")
+	}
+	return result, nil
+}
+
+// GenClientAuth print a x25519 client auth keypair for the onion service.
+// Private key is in tor .auth_private format, public key in .auth format.
+func GenClientAuth(onion string) error {
+	onion = strings.TrimSuffix(strings.ToLower(onion), ".onion")
+	if len(onion) != 56 {
+		return errors.New("invalid v3 onion address")
+	}
+
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Private (%s.auth_private): %s:%s%s\n", onion, onion, clientAuthKeyPrefix, base32NoPadding.EncodeToString(priv.Bytes()))
+	fmt.Printf("Public (.auth): %s%s\n", clientAuthKeyPrefix, base32NoPadding.EncodeToString(priv.PublicKey().Bytes()))
+	return nil
+}
+
+// authOnion is an onion service with v3 client authorization.
+// bine does not support ClientAuthV3, ADD_ONION is sent directly on the control connection.
+type authOnion struct {
+	sync.Mutex
+	t          *tor.Tor
+	key        *control.ED25519Key
+	localAddr  string
+	remotePort int
+	serviceID  string
+	publicKeys []string
+}
+
+// listenWithClientAuth create an onion service reachable only by clients of config file
+func listenWithClientAuth(ctx context.Context, t *tor.Tor, key ed25519.PrivateKey, remotePort int, filename string) (*tor.OnionService, error) {
+	config, err := ClientAuthLoad(filename)
+	if err != nil {
+		return nil, err
+	}
+	publicKeys, err := config.PublicKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	onion := &authOnion{
+		t:          t,
+		key:        &control.ED25519Key{KeyPair: torEd25519.FromCryptoPrivateKey(key)},
+		localAddr:  listener.Addr().String(),
+		remotePort: remotePort,
+	}
+	err = onion.add(publicKeys)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	log.WithField("Clients", len(publicKeys)).Info("Onion service client authorization enabled")
+
+	go onion.watch(ctx, filename)
+
+	return &tor.OnionService{
+		ID:                        onion.serviceID,
+		Key:                       onion.key.KeyPair,
+		Version3:                  true,
+		LocalListener:             listener,
+		RemotePorts:               []int{remotePort},
+		CloseLocalListenerOnClose: true,
+		Tor:                       t,
+	}, nil
+}
+
+// add (re)create onion service with authorized clients public keys.
+// Service id is derived from the key, tor rejects a second ADD_ONION for the same id:
+// the service is deleted first and restored with previous clients if the new one can't be added.
+func (p *authOnion) add(publicKeys []string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if len(p.serviceID) > 0 {
+		err := p.t.Control.DelOnion(p.serviceID)
+		if err != nil {
+			return err
+		}
+		p.serviceID = ""
+	}
+
+	err := p.addOnion(publicKeys)
+	if err != nil && len(p.publicKeys) > 0 {
+		restoreErr := p.addOnion(p.publicKeys)
+		if restoreErr != nil {
+			log.WithError(restoreErr).Error("Failed to restore onion service with previous clients")
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	p.publicKeys = publicKeys
+	return nil
+}
+
+// addOnion send ADD_ONION with client authorization, serviceID is set on success
+func (p *authOnion) addOnion(publicKeys []string) error {
+	cmd := "ADD_ONION " + string(p.key.Type()) + ":" + p.key.Blob()
+	cmd += " Flags=V3Auth"
+	cmd += " Port=" + strconv.Itoa(p.remotePort) + "," + p.localAddr
+	for _, publicKey := range publicKeys {
+		cmd += " ClientAuthV3=" + publicKey
+	}
+	resp, err := p.t.Control.SendRequest(cmd)
+	if err != nil {
+		return err
+	}
+	for _, data := range resp.Data {
+		if serviceID, ok := strings.CutPrefix(data, "ServiceID="); ok {
+			p.serviceID = serviceID
+		}
+	}
+	if len(p.serviceID) == 0 {
+		return errors.New("onion service ID not found")
+	}
+	return nil
+}
+
+// watch reload authorized clients on config file change
+func (p *authOnion) watch(ctx context.Context, filename string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithError(err).WithField("Filename", filename).Error("Failed to create client auth watcher")
+		return
+	}
+	defer watcher.Close()
+
+	err = watcher.Add(filename)
+	if err != nil {
+		log.WithError(err).WithField("Filename", filename).Error("Failed to watch client auth file")
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Write != fsnotify.Write {
+				continue
+			}
+			log.Info("Reloading client auth file")
+
+			config, err := ClientAuthLoad(filename)
+			if err != nil {
+				log.WithError(err).WithField("Filename", filename).Error("Failed to load client auth file")
+				continue
+			}
+			// keep previous clients on invalid config, onion service is never made public
+			publicKeys, err := config.PublicKeys()
+			if err != nil {
+				log.WithError(err).WithField("Filename", filename).Error("Invalid client auth file")
+				continue
+			}
+			err = p.add(publicKeys)
+			if err != nil {
+				log.WithError(err).Error("Failed to update onion service client authorization")
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).WithField("Filename", filename).Error("Client auth watcher error")
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
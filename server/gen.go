@@ -2,18 +2,18 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"os"
-	"regexp"
+	"path/filepath"
 	"runtime"
 	"strings"
-
-	"encoding/base32"
-	"encoding/base64"
-	"encoding/hex"
-
-	"crypto/rand"
-	"crypto/sha512"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -22,83 +22,202 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-func GenKey(prefix string, count int) {
-	found := make(chan bool)
+// onion addresses use lowercase base32
+const onionAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
 
-	go func(found chan bool) {
-		for range found {
-			count--
-			if count == 0 {
-				os.Exit(0)
-			}
-		}
+// only the first 51 characters of an onion address depend on the public key
+const maxPrefixLength = 51
 
-	}(found)
+// interval between progress reports
+const genReportInterval = 10 * time.Second
 
+var onionEncoding = base32.NewEncoding(onionAlphabet).WithPadding(base32.NoPadding)
+
+// GenKey search keys with onion address starting with prefix and write them to outputDir.
+// Search stops after count keys, 0 for no limits.
+func GenKey(prefix string, count int, outputDir string) error {
 	if prefix == "*" {
 		prefix = ""
 	}
-	r := regexp.MustCompile(".*")
-	if len(prefix) > 0 {
-		r = regexp.MustCompile(fmt.Sprintf("^(?i)%s", prefix))
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	err := validatePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(outputDir, 0700)
+	if err != nil {
+		return err
 	}
+
+	expected := math.Pow(32, float64(len(prefix)))
+	log.WithFields(log.Fields{
+		"Prefix":   prefix,
+		"Expected": fmt.Sprintf("%.0f keys", expected),
+		"Output":   outputDir,
+	}).Info("Searching onion address")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Uint64
+	found := make(chan []byte)
+
+	var wg sync.WaitGroup
 	for index := 0; index < runtime.NumCPU(); index++ {
-		go search(index, r, found)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			search(ctx, prefix, &attempts, found)
+		}()
 	}
 
-	<-context.Background().Done()
+	ticker := time.NewTicker(genReportInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	var foundCount int
+	for {
+		select {
+		case seed := <-found:
+			foundCount++
+			err := writeKey(outputDir, seed)
+			if err != nil {
+				cancel()
+				wg.Wait()
+				return err
+			}
+			if count > 0 && foundCount >= count {
+				cancel()
+				wg.Wait()
+				return nil
+			}
+
+		case <-ticker.C:
+			elapsed := time.Since(start)
+			rate := float64(attempts.Load()) / elapsed.Seconds()
+			fields := log.Fields{
+				"Found":    foundCount,
+				"Keys/sec": fmt.Sprintf("%.0f", rate),
+				"Elapsed":  elapsed.Round(time.Second),
+			}
+			if rate > 0 {
+				fields["ExpectedPerKey"] = time.Duration(expected / rate * float64(time.Second)).Round(time.Second)
+			}
+			log.WithFields(fields).Info("Searching onion address")
+		}
+	}
 }
 
-// Hidden service version
-const version = byte(0x03)
+func validatePrefix(prefix string) error {
+	if len(prefix) > maxPrefixLength {
+		return fmt.Errorf("prefix is longer than %d characters", maxPrefixLength)
+	}
+	for _, c := range prefix {
+		if !strings.ContainsRune(onionAlphabet, c) {
+			return fmt.Errorf("invalid prefix character %q, onion addresses use base32 (a-z, 2-7)", c)
+		}
+	}
+	return nil
+}
 
-// Salt used to create checkdigits
-const salt = ".onion checksum"
+// search generate keys until one matches prefix, only the public key is encoded
+func search(ctx context.Context, prefix string, attempts *atomic.Uint64, found chan []byte) {
+	const batch = 1024
+
+	encoded := make([]byte, onionEncoding.EncodedLen(ed25519.PublicKeySize))
+	seeds := make([]byte, batch*ed25519.SeedSize)
 
-func search(id int, r *regexp.Regexp, found chan bool) {
-	count := 0
 	for {
-		count++
-		pub, pri, err := ed25519.GenerateKey(rand.Reader)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		_, err := rand.Read(seeds)
 		if err != nil {
-			log.Fatal(err)
+			log.WithError(err).Error("Failed to read random")
+			return
 		}
+		for i := 0; i < batch; i++ {
+			seed := seeds[i*ed25519.SeedSize : (i+1)*ed25519.SeedSize]
+			pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
 
-		if r.MatchString(base32.StdEncoding.EncodeToString(pub[:])) {
-			priv, err := crypto.UnmarshalSecp256k1PrivateKey(pri.Seed())
-			if err != nil {
-				log.Fatal(err)
+			if string(encodePrefix(encoded, pub, len(prefix))) != prefix {
+				continue
 			}
 
-			peerid, err := peer.IDFromPrivateKey(priv)
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Println()
-			fmt.Println("Address:", getServiceID(pub)+".onion")
-			fmt.Println("Peer ID:", peerid.String())
-			fmt.Println("Private Key:", expandKey(pri))
-			fmt.Println("Seed: ", hex.EncodeToString(pri.Seed()))
-			found <- true
-		} else {
-			if id == 0 && count%10000 == 0 {
-				log.Println("Count: ", count)
+			result := make([]byte, ed25519.SeedSize)
+			copy(result, seed)
+			select {
+			case found <- result:
+			case <-ctx.Done():
+				return
 			}
 		}
+		attempts.Add(batch)
 	}
 }
 
-// Expand ed25519.PrivateKey to (a || RH) form, return base64
-func expandKey(pri ed25519.PrivateKey) string {
-	h := sha512.Sum512(pri[:32])
-	// Set bits so that h[:32] is private scalar "a"
-	h[0] &= 248
-	h[31] &= 127
-	h[31] |= 64
-	// Since h[32:] is RH, h is now (a || RH)
-	return base64.StdEncoding.EncodeToString(h[:])
+// encodePrefix return the first length characters of the onion address of pub, encoded in dst.
+// Only enough bytes of the public key to compare the prefix are encoded.
+func encodePrefix(dst []byte, pub ed25519.PublicKey, length int) []byte {
+	prefixBytes := (length*5 + 7) / 8
+	onionEncoding.Encode(dst, pub[:prefixBytes])
+	return dst[:length]
 }
 
+// writeKey write seed, tor hidden service secret key and peer ID to a directory named after the onion address
+func writeKey(outputDir string, seed []byte) error {
+	pri := ed25519.NewKeyFromSeed(seed)
+	pub := pri.Public().(ed25519.PublicKey)
+	onion := getServiceID(pub) + ".onion"
+
+	priv, err := crypto.UnmarshalSecp256k1PrivateKey(seed)
+	if err != nil {
+		return err
+	}
+	peerID, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	secret, err := ExportHiddenServiceSecret(hex.EncodeToString(seed))
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(outputDir, onion)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		"hostname":              []byte(onion + "\n"),
+		"seed":                  []byte(hex.EncodeToString(seed) + "\n"),
+		"hs_ed25519_secret_key": secret,
+		"peer_id":               []byte(peerID.String() + "\n"),
+	}
+	for name, data := range files {
+		err = os.WriteFile(filepath.Join(dir, name), data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"Address": onion,
+		"PeerID":  peerID.String(),
+	}).Info("Onion address found")
+	fmt.Println(dir)
+	return nil
+}
+
+// Hidden service version
+const version = byte(0x03)
+
+// Salt used to create checkdigits
+const salt = ".onion checksum"
+
 func getCheckdigits(pub ed25519.PublicKey) []byte {
 	// Calculate checksum sha3(".onion checksum" || publicKey || version)
 	checkstr := []byte(salt)
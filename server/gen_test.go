@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func Test_validatePrefix(t *testing.T) {
+	valid := []string{"", "a", "sor", "abcdefghijklmnopqrstuvwxyz234567", strings.Repeat("a", maxPrefixLength)}
+	for _, prefix := range valid {
+		if err := validatePrefix(prefix); err != nil {
+			t.Errorf("validatePrefix(%s) = %v", prefix, err)
+		}
+	}
+	invalid := []string{"0", "1", "8", "9", "abc1", "A", "so-", "é", strings.Repeat("a", maxPrefixLength+1)}
+	for _, prefix := range invalid {
+		if err := validatePrefix(prefix); err == nil {
+			t.Errorf("validatePrefix(%s) must fail", prefix)
+		}
+	}
+}
+
+func Test_encodePrefix(t *testing.T) {
+	encoded := make([]byte, onionEncoding.EncodedLen(ed25519.PublicKeySize))
+	for i := 0; i < 100; i++ {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serviceID := getServiceID(pub)
+		for _, length := range []int{1, 3, 16, maxPrefixLength} {
+			if got := string(encodePrefix(encoded, pub, length)); got != serviceID[:length] {
+				t.Fatalf("encodePrefix(%d) = %s, want %s", length, got, serviceID[:length])
+			}
+		}
+	}
+}
+
+func Test_search(t *testing.T) {
+	for _, prefix := range []string{"s", "so2"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		found := make(chan []byte)
+		var attempts atomic.Uint64
+		go search(ctx, prefix, &attempts, found)
+
+		select {
+		case seed := <-found:
+			pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+			if serviceID := getServiceID(pub); !strings.HasPrefix(serviceID, prefix) {
+				t.Errorf("search(%s) = %s", prefix, serviceID)
+			}
+		case <-ctx.Done():
+			t.Errorf("search(%s) timeout", prefix)
+		}
+		cancel()
+	}
+}
@@ -1,15 +1,48 @@
 package server
 
 import (
+	"context"
 	"errors"
 
 	soroban "code.samourai.io/wallet/samourai-soroban"
-	"code.samourai.io/wallet/samourai-soroban/services"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/ipc"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+
+	log "github.com/sirupsen/logrus"
 )
 
-func addToDirectory(directory soroban.Directory, args *services.DirectoryEntry) error {
+func addToDirectory(directory soroban.Directory, args *api.DirectoryEntry) error {
 	if args == nil {
 		return errors.New("invalid args")
 	}
 	return directory.Add(args.Name, args.Entry, directory.TimeToLive(args.Mode))
 }
+
+// queryShard forward list request from IPC server to peers subscribed to key shard
+func queryShard(ctx context.Context, p2P *p2p.P2P, messageType ipc.MessageType, p2pMessage p2p.Message) ipc.Message {
+	var args api.DirectoryEntries
+	err := unmarshalData(p2pMessage.Payload, &args)
+	if err != nil {
+		log.WithError(err).Error("Failed to Unmarshal IPC message")
+		return ipc.Message{
+			Type:    messageType,
+			Message: "error",
+		}
+	}
+
+	response, err := p2P.Query(ctx, args.Name, p2pMessage.Payload)
+	if err != nil {
+		log.WithError(err).Warning("Failed to query shard")
+		return ipc.Message{
+			Type:    messageType,
+			Message: "error",
+		}
+	}
+
+	return ipc.Message{
+		Type:    messageType,
+		Message: "success",
+		Payload: string(response),
+	}
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+
+	"github.com/cretz/bine/tor"
+	"github.com/rs/cors"
+	log "github.com/sirupsen/logrus"
+)
+
+// maximum rpc request size read by method allow-list
+const maxRequestSize = 1 << 20
+
+// StartListeners serve rpc on configured listeners instead of default listeners
+func (p *Soroban) StartListeners(ctx context.Context, listeners []soroban.ListenerInfo) error {
+	router := p.router()
+
+	for i, info := range listeners {
+		if len(info.Name) == 0 {
+			info.Name = fmt.Sprintf("%s-%d", info.Type, i)
+		}
+
+		var listener net.Listener
+		var err error
+		switch info.Type {
+		case soroban.ListenerOnion:
+			if info.Port == 0 {
+				info.Port = 80
+			}
+			var onion *tor.OnionService
+			onion, err = p.listenOnion(ctx, info.Seed, info.Port, info.ClientAuth)
+			if err != nil {
+				break
+			}
+			// first onion is the node ID
+			if p.onion == nil {
+				p.onion = onion
+			}
+			p.onions = append(p.onions, onion)
+			log.WithField("Listener", info.Name).Infof("Soroban listening: http://%s.onion:%d", onion.ID, info.Port)
+			listener = onion
+
+		case soroban.ListenerTCP:
+			listener, err = listenTCP(info)
+
+		case soroban.ListenerUnix:
+			listener, err = listenUnix(info)
+
+		default:
+			err = fmt.Errorf("unknown listener type: %s", info.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("listener %s: %w", info.Name, err)
+		}
+
+		handler := p.listenerHandler(router, info)
+		go func(name string, listener net.Listener) {
+			server := p.createHttpServer("", handler, ListenerType(name))
+			err := server.Serve(listener)
+			if err != http.ErrServerClosed {
+				log.WithError(err).WithField("Listener", name).Error("Http Server exited")
+			}
+		}(info.Name, listener)
+	}
+
+	p.started <- true
+	return nil
+}
+
+func listenTCP(info soroban.ListenerInfo) (net.Listener, error) {
+	addr := fmt.Sprintf("%s:%d", info.Hostname, info.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if info.SelfSigned || len(info.CertFile) > 0 || len(info.KeyFile) > 0 {
+		config, err := tlsConfig(info)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		listener = tls.NewListener(listener, config)
+		scheme = "https"
+	}
+	log.WithField("Listener", info.Name).Infof("Soroban listening: %s://%s/", scheme, addr)
+
+	return listener, nil
+}
+
+// listenUnix listen on unix socket with file mode, stale socket file is removed.
+// Socket in use by another process is not removed.
+func listenUnix(info soroban.ListenerInfo) (net.Listener, error) {
+	if len(info.Path) == 0 {
+		return nil, errors.New("unix socket path is required")
+	}
+	mode := uint64(0660)
+	if len(info.Mode) > 0 {
+		var err error
+		mode, err = strconv.ParseUint(info.Mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unix socket mode: %s", info.Mode)
+		}
+	}
+
+	if stat, err := os.Stat(info.Path); err == nil {
+		if stat.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", info.Path)
+		}
+		conn, err := net.DialTimeout("unix", info.Path, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s is already in use", info.Path)
+		}
+		os.Remove(info.Path)
+	}
+
+	listener, err := net.Listen("unix", info.Path)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(info.Path, os.FileMode(mode))
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	log.WithField("Listener", info.Name).Infof("Soroban listening: unix://%s", info.Path)
+
+	return listener, nil
+}
+
+// listenerHandler apply listener cors policy and method allow-list
+func (p *Soroban) listenerHandler(router http.Handler, info soroban.ListenerInfo) http.Handler {
+	allowedOrigins := info.CORS.AllowedOrigins
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"}
+	}
+	allowCredentials := info.CORS.AllowCredentials
+	if len(info.CORS.AllowedOrigins) == 0 {
+		allowCredentials = true // default policy
+	}
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: allowCredentials,
+	})
+
+	if len(info.Methods) == 0 {
+		return c.Handler(router)
+	}
+	return c.Handler(allowMethods(router, info.Methods))
+}
+
+// allowMethods reject rpc requests for methods not matching patterns (e.g. directory.List, directory.*)
+func allowMethods(next http.Handler, patterns []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rpc" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+
+		var request struct {
+			Method string `json:"method"`
+			ID     any    `json:"id"`
+		}
+		err = json.Unmarshal(data, &request)
+		if err != nil || !methodAllowed(request.Method, patterns) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{
+				"jsonrpc": "2.0",
+				"error":   map[string]any{"code": -32601, "message": "method not allowed"},
+				"id":      request.ID,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func methodAllowed(method string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(method)); ok {
+			return true
+		}
+	}
+	return false
+}
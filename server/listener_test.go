@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+)
+
+func Test_listenUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "soroban")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	info := soroban.ListenerInfo{Name: "unix", Type: soroban.ListenerUnix, Path: filepath.Join(dir, "soroban.sock")}
+
+	listener, err := listenUnix(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(info); err == nil {
+		t.Error("listenUnix() socket in use must fail")
+	}
+	if _, err := os.Stat(info.Path); err != nil {
+		t.Errorf("socket in use removed: %v", err)
+	}
+	listener.Close()
+
+	// stale socket file left by a killed process
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: info.Path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+	listener, err = listenUnix(info)
+	if err != nil {
+		t.Fatalf("listenUnix() stale socket = %v", err)
+	}
+	listener.Close()
+
+	info.Path = filepath.Join(dir, "file")
+	os.WriteFile(info.Path, nil, 0600)
+	if _, err := listenUnix(info); err == nil {
+		t.Error("listenUnix() regular file must fail")
+	}
+}
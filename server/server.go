@@ -15,6 +15,7 @@ import (
 	"crypto/rand"
 
 	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
 	"code.samourai.io/wallet/samourai-soroban/confidential"
 	"code.samourai.io/wallet/samourai-soroban/internal"
 	"code.samourai.io/wallet/samourai-soroban/ipc"
@@ -25,7 +26,6 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/rpc"
 	gjson "github.com/gorilla/rpc/json"
-	"github.com/rs/cors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -34,9 +34,15 @@ type Soroban struct {
 	ipc       *ipc.IPCService
 	directory soroban.Directory
 	t         *tor.Tor
-	onion     *tor.OnionService
-	started   chan bool
-	rpcServer *rpc.Server
+	// torExternal is set when t is the external tor shared with p2p
+	torExternal bool
+	onion       *tor.OnionService
+	onions      []*tor.OnionService
+	options     soroban.SorobanInfo
+	stats       *Stats
+	started     chan bool
+	rpcServer   *rpc.Server
+	signer      ed25519.PrivateKey
 }
 
 func New(ctx context.Context, options soroban.Options) (context.Context, *Soroban) {
@@ -58,7 +64,7 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 
 	startIPCService := options.IPC.ChildProcessCount > 0 && options.IPC.ChildID == 0
 	startMainSoroban := startIPCService || (options.IPC.ChildProcessCount == 0 && options.IPC.ChildID == 0)
-	startP2PDirectory := len(options.P2P.Bootstrap) > 0 && (options.IPC.ChildProcessCount == 0 || options.IPC.ChildID > 0)
+	startP2PDirectory := (len(options.P2P.Bootstrap) > 0 || options.P2P.MDNS) && (options.IPC.ChildProcessCount == 0 || options.IPC.ChildID > 0)
 
 	ipcMode := "peer"
 	if !startMainSoroban {
@@ -67,9 +73,34 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 
 	ctx = context.WithValue(ctx, internal.SorobanDirectoryKey, directory)
 
+	if seed := options.Soroban.NodeSigningSeed(); len(seed) > 0 {
+		data, err := hex.DecodeString(seed)
+		if err != nil {
+			log.WithError(err).Fatal("Invalid signing seed")
+		}
+		signer := ed25519.NewKeyFromSeed(data)
+		ctx = context.WithValue(ctx, internal.SorobanSignerKey, signer)
+		log.WithField("PublicKey", hex.EncodeToString(signer.Public().(ed25519.PublicKey))).Info("Directory responses are signed")
+	}
+
+	// resolve p2p identity once, child processes receive the seed from a file descriptor
+	seed, err := p2p.ResolveSeed(options.P2P)
+	if err != nil {
+		log.WithError(err).Fatal("Invalid P2P identity")
+	}
+	options.P2P.Seed = seed
+
+	sharding, err := p2p.NewSharding(options.P2P.Shards, options.P2P.ShardSubscribe, options.P2P.ShardMiss)
+	if err != nil {
+		log.WithError(err).Fatal("Invalid Sharding")
+	}
+
 	ctx = context.WithValue(ctx, internal.SorobanP2PKey, &p2p.P2P{
 		OnMessage: make(chan p2p.Message),
 		ChildID:   options.IPC.ChildID,
+		Domain:    options.Soroban.Domain,
+		Sharding:  sharding,
+		Tor:       options.Tor,
 	})
 
 	if options.IPC.ChildProcessCount > 0 || options.IPC.ChildID > 0 {
@@ -91,19 +122,12 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 	}).Debug("IPC info")
 
 	if startIPCService {
-		// start IPC directory
+		// start IPC directory, child processes are spawned by the caller
 		log.Info("Start IPC Server")
 		ready := make(chan struct{})
 
 		go services.StartIPCService(ctx, ready)
 		<-ready
-		log.Info("Starting child process")
-
-		// spawn p2p child process
-		for i := 0; i < options.IPC.ChildProcessCount; i++ {
-			startChildSoroban(ctx, options, i+1)
-			<-time.After(5 * time.Second)
-		}
 	}
 
 	// Both IPC Server & client need to connect to IPC server (bi-directionnal communcation)
@@ -120,6 +144,24 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 			if client := internal.IPCFromContext(ctx); client != nil {
 				go client.ListenFromServer(ctx, options.IPC.Subject, func(ctx context.Context, message ipc.Message) (ipc.Message, error) {
 					switch message.Type {
+					case ipc.MessageTypeReload:
+						var reloaded soroban.ReloadableOptions
+						err := unmarshalString(message.Payload, &reloaded)
+						if err != nil {
+							log.WithError(err).Error("Failed to Unmarshal reload message")
+							return ipc.Message{
+								Type:    message.Type,
+								Message: "error",
+							}, nil
+						}
+
+						log.Info("Reloading options from IPC server")
+						services.ApplyOptions(ctx, options.WithReloadableOptions(reloaded))
+						return ipc.Message{
+							Type:    message.Type,
+							Message: "success",
+						}, nil
+
 					case ipc.MessageTypeIPC:
 						log.Debug("IPC Message recieved from server")
 
@@ -136,7 +178,11 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 
 						// forward message to p2p network
 						p2P := internal.P2PFromContext(ctx)
-						var args services.DirectoryEntry
+
+						if p2pMessage.Context == "Directory.List" {
+							return queryShard(ctx, p2P, message.Type, p2pMessage), nil
+						}
+						var args api.DirectoryEntry
 						err = unmarshalData(p2pMessage.Payload, &args)
 						if err != nil {
 							log.WithError(err).Error("Failed to Unmarshal IPC message")
@@ -148,7 +194,7 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 
 						log.WithField("p2pMessage", fmt.Sprintf("%s: %s", p2pMessage.Context, string(p2pMessage.Payload))).Debug("Publish Message to p2p")
 
-						err = p2P.PublishJson(ctx, p2pMessage.Context, &args)
+						err = p2P.PublishMessageToShard(ctx, args.Name, p2pMessage)
 						if err != nil {
 							log.WithError(err).Error("Failed to Publish P2P message")
 							return ipc.Message{
@@ -179,6 +225,11 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 		log.Info("P2PDirectory service started")
 	}
 
+	// child processes use admin port + child ID
+	if options.Soroban.AdminPort > 0 {
+		go startAdminServer(ctx, options.Soroban.AdminPort+options.IPC.ChildID)
+	}
+
 	if !startMainSoroban {
 		// soroban is in child mode
 		return ctx, nil
@@ -186,9 +237,9 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 
 	// start soroban service
 	var t *tor.Tor
-	if options.Soroban.WithTor {
+	if options.Soroban.WithTor || options.HasOnionListener() {
 		var err error
-		t, err = tor.Start(ctx, &tor.StartConf{
+		t, err = soroban.StartTor(ctx, options.Tor, "soroban", &tor.StartConf{
 			DebugWriter:     io.Discard,
 			TempDataDirBase: "/tmp",
 		})
@@ -196,11 +247,11 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 			log.WithError(err).Error("tor.Start error")
 			return ctx, nil
 		}
-		t.DeleteDataDirOnClose = true
-		soroban.AddTorClient(ctx, t)
-		// wait for network ready
-		log.Info("Waiting for soroban tor network")
-		t.EnableNetwork(ctx, true)
+		if !options.Tor.External() {
+			// wait for network ready
+			log.Info("Waiting for soroban tor network")
+			t.EnableNetwork(ctx, true)
+		}
 	}
 
 	rpcServer := rpc.NewServer()
@@ -208,20 +259,44 @@ func New(ctx context.Context, options soroban.Options) (context.Context, *Soroba
 	rpcServer.RegisterCodec(gjson.NewCodec(), "application/json")
 	rpcServer.RegisterCodec(gjson.NewCodec(), "application/json;charset=UTF-8")
 
-	http.Handle("/rpc", rpcServer)
-
 	return ctx, &Soroban{
-		p2p:       internal.P2PFromContext(ctx),
-		ipc:       internal.IPCFromContext(ctx),
-		t:         t,
-		started:   make(chan bool),
-		rpcServer: rpcServer,
-		directory: directory,
+		p2p:         internal.P2PFromContext(ctx),
+		ipc:         internal.IPCFromContext(ctx),
+		t:           t,
+		torExternal: options.Tor.External(),
+		options:     options.Soroban,
+		stats:       NewStats(),
+		started:     make(chan bool),
+		rpcServer:   rpcServer,
+		directory:   directory,
+		signer:      internal.SignerFromContext(ctx),
 	}
 }
 
 /// Soroban interface
 
+// AnnounceSigner return the onion key, or the node signing key, nil if none is available
+func (p *Soroban) AnnounceSigner() crypto.Signer {
+	if p.onion != nil {
+		if signer, ok := p.onion.Key.(crypto.Signer); ok {
+			return signer
+		}
+	}
+	if p.signer != nil {
+		return p.signer
+	}
+	return nil
+}
+
+// Load return the number of rpc requests during the last minute
+func (p *Soroban) Load() int {
+	var result int
+	for _, listenerType := range p.stats.ListenerTypes() {
+		result += p.stats.CountRequests(listenerType, time.Minute)
+	}
+	return result
+}
+
 func (p *Soroban) ID() string {
 	if p.onion == nil {
 		return ""
@@ -242,8 +317,23 @@ func (p *Soroban) Start(ctx context.Context, hostname string, port int) error {
 }
 
 func (p *Soroban) StartWithTor(ctx context.Context, hostname string, port int, seed string) error {
+	var err error
+	p.onion, err = p.listenOnion(ctx, seed, 80, p.options.ClientAuth)
+	if err != nil {
+		return err
+	}
+	p.onions = append(p.onions, p.onion)
+
+	// start with listener
+	go p.startServer(hostname, port, p.onion)
+
+	return nil
+}
+
+// listenOnion publish an onion service on remote port with key from seed, a random key is used if seed is empty
+func (p *Soroban) listenOnion(ctx context.Context, seed string, remotePort int, clientAuth string) (*tor.OnionService, error) {
 	if p.t == nil {
-		return errors.New("tor not initialized")
+		return nil, errors.New("tor not initialized")
 	}
 
 	if len(seed) == 0 {
@@ -258,49 +348,30 @@ func (p *Soroban) StartWithTor(ctx context.Context, hostname string, port int, s
 	if len(seed) > 0 {
 		str, err := hex.DecodeString(seed)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		key = ed25519.NewKeyFromSeed(str)
 	}
 
+	if len(clientAuth) > 0 {
+		return listenWithClientAuth(ctx, p.t, key.(ed25519.PrivateKey), remotePort, clientAuth)
+	}
+
 	// Wait at most a few minutes to publish the tor hidden service
 	listenCtx, listenCancel := context.WithTimeout(context.Background(), 3*time.Minute)
 	defer listenCancel()
 
-	var err error
-	p.onion, err = p.t.Listen(listenCtx,
+	return p.t.Listen(listenCtx,
 		&tor.ListenConf{
-			RemotePorts: []int{80},
+			RemotePorts: []int{remotePort},
 			Key:         key,
 		})
-	if err != nil {
-		return err
-	}
-
-	// start with listener
-	go p.startServer(hostname, port, p.onion)
-
-	return nil
 }
 
 func (p *Soroban) startServer(hostname string, port int, listener net.Listener) {
 	p.started <- true
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"}, // Use your allowed origin here
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-		AllowCredentials: true,
-	})
 
-	stats := NewStats()
-	rpcHandler := WrapHandler(stats.Middleware((p.rpcServer)))
-
-	router := mux.NewRouter()
-	router.HandleFunc("/rpc", rpcHandler)
-	router.HandleFunc("/stats", stats.StatsHandler)
-	router.HandleFunc("/status", StatusHandler)
-
-	mainHandler := c.Handler(router)
+	mainHandler := p.listenerHandler(p.router(), soroban.ListenerInfo{})
 
 	if listener != nil {
 		go func() {
@@ -312,26 +383,71 @@ func (p *Soroban) startServer(hostname string, port int, listener net.Listener)
 		}()
 	}
 
-	addr := fmt.Sprintf("%s:%d", hostname, port)
-	ipv4Server := p.createHttpServer(addr, mainHandler, IPv4Listener)
-	err := ipv4Server.ListenAndServe()
+	if len(p.options.UnixSocket) > 0 {
+		unixListener, err := listenUnix(soroban.ListenerInfo{
+			Name: string(UnixListener),
+			Path: p.options.UnixSocket,
+			Mode: p.options.UnixMode,
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to listen on unix socket")
+		} else {
+			go func() {
+				unixServer := p.createHttpServer("", mainHandler, UnixListener)
+				err := unixServer.Serve(unixListener)
+				if err != http.ErrServerClosed {
+					log.WithError(err).Error("Unix Http Server exited")
+				}
+			}()
+		}
+	}
+
+	ipv4Listener, err := listenTCP(soroban.ListenerInfo{
+		Name:       string(IPv4Listener),
+		Hostname:   hostname,
+		Port:       port,
+		CertFile:   p.options.TLSCertFile,
+		KeyFile:    p.options.TLSKeyFile,
+		SelfSigned: p.options.TLSSelfSigned,
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to listen on IPv4")
+		return
+	}
+	ipv4Server := p.createHttpServer("", mainHandler, IPv4Listener)
+	err = ipv4Server.Serve(ipv4Listener)
 	if err != http.ErrServerClosed {
 		log.WithError(err).Error("IPv4 Http Server exited")
 	}
 }
 
+// router serve rpc, stats and status for all listeners
+func (p *Soroban) router() http.Handler {
+	rpcHandler := WrapHandler(p.stats.Middleware((p.rpcServer)))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/rpc", rpcHandler)
+	router.HandleFunc("/stats", p.stats.StatsHandler)
+	router.HandleFunc("/status", StatusHandler)
+
+	return router
+}
+
 func (p *Soroban) Stop(ctx context.Context) {
-	if p.onion == nil {
+	if len(p.onions) == 0 {
 		return
 	}
-	err := p.onion.Close()
-	if err != nil {
-		log.WithError(err).Error("Fails to Close tor")
+	for _, onion := range p.onions {
+		err := onion.Close()
+		if err != nil {
+			log.WithError(err).Error("Fails to Close tor")
+		}
 	}
-	if p.t == nil {
+	// external tor is shared with p2p, it is closed by soroban.Shutdown
+	if p.t == nil || p.torExternal {
 		return
 	}
-	err = p.t.Close()
+	err := p.t.Close()
 	if err != nil {
 		log.WithError(err).Error("Fails to Close tor")
 	}
@@ -354,6 +470,9 @@ func (p *Soroban) createHttpServer(addr string, handler http.Handler, listenerTy
 			if p.ipc != nil {
 				ctx = context.WithValue(ctx, internal.SorobanIPCKey, p.ipc)
 			}
+			if p.signer != nil {
+				ctx = context.WithValue(ctx, internal.SorobanSignerKey, p.signer)
+			}
 
 			return ctx
 		},
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,18 +18,20 @@ const (
 
 	IPv4Listener ListenerType = "IPv4"
 	TorListener  ListenerType = "Tor"
+	UnixListener ListenerType = "Unix"
 )
 
 type Stats struct {
 	sync.RWMutex
-	IPv4 []int64
-	Tor  []int64
+	requests map[ListenerType][]int64
 }
 
 func NewStats() *Stats {
 	return &Stats{
-		IPv4: make([]int64, 0),
-		Tor:  make([]int64, 0),
+		requests: map[ListenerType][]int64{
+			IPv4Listener: make([]int64, 0),
+			TorListener:  make([]int64, 0),
+		},
 	}
 }
 
@@ -36,13 +40,7 @@ func (s *Stats) RecordRequest(listenerType ListenerType) {
 	defer s.Unlock()
 	now := time.Now().Unix()
 
-	switch listenerType {
-	case IPv4Listener:
-		s.IPv4 = append(s.IPv4, now)
-
-	case TorListener:
-		s.Tor = append(s.Tor, now)
-	}
+	s.requests[listenerType] = append(s.requests[listenerType], now)
 }
 
 func (s *Stats) CountRequests(listenerType ListenerType, duration time.Duration) int {
@@ -52,16 +50,7 @@ func (s *Stats) CountRequests(listenerType ListenerType, duration time.Duration)
 	threshold := now - int64(duration.Seconds())
 	count := 0
 
-	requests := []int64{}
-	switch listenerType {
-	case IPv4Listener:
-		requests = s.IPv4
-
-	case TorListener:
-		requests = s.Tor
-	}
-
-	for _, v := range requests {
+	for _, v := range s.requests[listenerType] {
 		if v >= threshold {
 			count++
 		}
@@ -69,28 +58,37 @@ func (s *Stats) CountRequests(listenerType ListenerType, duration time.Duration)
 	return count
 }
 
+// ListenerTypes return listener types with recorded requests
+func (s *Stats) ListenerTypes() []ListenerType {
+	s.RLock()
+	defer s.RUnlock()
+
+	var result []ListenerType
+	for listenerType := range s.requests {
+		result = append(result, listenerType)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
 func (s *Stats) Cleanup(duration time.Duration) {
 	s.Lock()
 	defer s.Unlock()
 	startDate := time.Now().Add(-1 * duration).Unix()
-	thresholdIndex := 0
 
 	// Remove older entries
-	for i, v := range s.IPv4 {
-		if v > startDate {
-			thresholdIndex = i
-			break
-		}
-	}
-	s.IPv4 = s.IPv4[thresholdIndex:]
-
-	for i, v := range s.Tor {
-		if v > startDate {
-			thresholdIndex = i
-			break
+	for listenerType, requests := range s.requests {
+		thresholdIndex := len(requests)
+		for i, v := range requests {
+			if v > startDate {
+				thresholdIndex = i
+				break
+			}
 		}
+		s.requests[listenerType] = requests[thresholdIndex:]
 	}
-	s.Tor = s.Tor[thresholdIndex:]
 }
 
 func (s *Stats) Middleware(next http.Handler) http.Handler {
@@ -104,42 +102,35 @@ func (s *Stats) Middleware(next http.Handler) http.Handler {
 func (s *Stats) StatsHandler(w http.ResponseWriter, r *http.Request) {
 	s.Cleanup(24 * time.Hour)
 
-	ipv4 := map[string]int{
-		"last_01m": s.CountRequests(IPv4Listener, time.Minute),
-		"last_15m": s.CountRequests(IPv4Listener, 15*time.Minute),
-		"last_30m": s.CountRequests(IPv4Listener, 30*time.Minute),
-		"last_1h":  s.CountRequests(IPv4Listener, time.Hour),
-		"last_2h":  s.CountRequests(IPv4Listener, 2*time.Hour),
-		"last_3h":  s.CountRequests(IPv4Listener, 3*time.Hour),
-		"last_6h":  s.CountRequests(IPv4Listener, 6*time.Hour),
-		"last_12h": s.CountRequests(IPv4Listener, 12*time.Hour),
-		"last_24h": s.CountRequests(IPv4Listener, 24*time.Hour),
+	periods := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"last_01m", time.Minute},
+		{"last_15m", 15 * time.Minute},
+		{"last_30m", 30 * time.Minute},
+		{"last_1h", time.Hour},
+		{"last_2h", 2 * time.Hour},
+		{"last_3h", 3 * time.Hour},
+		{"last_6h", 6 * time.Hour},
+		{"last_12h", 12 * time.Hour},
+		{"last_24h", 24 * time.Hour},
 	}
 
-	tor := map[string]int{
-		"last_01m": s.CountRequests(TorListener, time.Minute),
-		"last_15m": s.CountRequests(TorListener, 15*time.Minute),
-		"last_30m": s.CountRequests(TorListener, 30*time.Minute),
-		"last_1h":  s.CountRequests(TorListener, time.Hour),
-		"last_2h":  s.CountRequests(TorListener, 2*time.Hour),
-		"last_3h":  s.CountRequests(TorListener, 3*time.Hour),
-		"last_6h":  s.CountRequests(TorListener, 6*time.Hour),
-		"last_12h": s.CountRequests(TorListener, 12*time.Hour),
-		"last_24h": s.CountRequests(TorListener, 24*time.Hour),
+	// totals and requests count by listener label (ipv4, tor, ...)
+	response := make(map[string]interface{})
+	totals := make(map[string]int)
+	for _, listenerType := range s.ListenerTypes() {
+		counts := make(map[string]int)
+		for _, period := range periods {
+			count := s.CountRequests(listenerType, period.duration)
+			counts[period.name] = count
+			totals[period.name] += count
+		}
+		response[strings.ToLower(string(listenerType))] = counts
 	}
-
-	response := map[string]interface{}{
-		"last_01m": ipv4["last_01m"] + tor["last_01m"],
-		"last_15m": ipv4["last_15m"] + tor["last_15m"],
-		"last_30m": ipv4["last_30m"] + tor["last_30m"],
-		"last_1h":  ipv4["last_1h"] + tor["last_1h"],
-		"last_2h":  ipv4["last_2h"] + tor["last_2h"],
-		"last_3h":  ipv4["last_3h"] + tor["last_6h"],
-		"last_6h":  ipv4["last_6h"] + tor["last_3h"],
-		"last_12h": ipv4["last_12h"] + tor["last_12h"],
-		"last_24h": ipv4["last_24h"] + tor["last_24h"],
-		"ipv4":     ipv4,
-		"tor":      tor,
+	for _, period := range periods {
+		response[period.name] = totals[period.name]
 	}
 
 	jsonResponse, err := json.Marshal(response)
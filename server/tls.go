@@ -0,0 +1,122 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// validity of self-signed certificates
+const selfSignedValidity = 10 * 365 * 24 * time.Hour
+
+// tlsConfig load certificate files, or a self-signed certificate.
+// Self-signed certificate is saved to certificate files when set, to keep the same fingerprint on restart.
+func tlsConfig(info soroban.ListenerInfo) (*tls.Config, error) {
+	var certificate tls.Certificate
+	var err error
+	switch {
+	case info.SelfSigned && !(fileExists(info.CertFile) && fileExists(info.KeyFile)):
+		certificate, err = selfSignedCertificate(info.Hostname, info.CertFile, info.KeyFile)
+
+	case len(info.CertFile) > 0 && len(info.KeyFile) > 0:
+		certificate, err = tls.LoadX509KeyPair(info.CertFile, info.KeyFile)
+
+	default:
+		err = errors.New("tls certificate and key files are required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.SelfSigned && len(certificate.Certificate) > 0 {
+		// clients pin the certificate with its fingerprint
+		log.WithField("Listener", info.Name).Infof("TLS certificate fingerprint (sha256): %s", CertificateFingerprint(certificate.Certificate[0]))
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// CertificateFingerprint return sha256 fingerprint of DER certificate
+func CertificateFingerprint(der []byte) string {
+	hash := sha256.Sum256(der)
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+func selfSignedCertificate(hostname, certFile, keyFile string) (tls.Certificate, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "soroban"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+	}
+	if ip := net.ParseIP(hostname); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if len(hostname) > 0 {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	if len(certFile) > 0 && len(keyFile) > 0 {
+		err = os.WriteFile(keyFile, keyPEM, 0600)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		err = os.WriteFile(certFile, certPEM, 0644)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return err == nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+)
+
+// AdminArgs for json-rpc request
+type AdminArgs struct{}
+
+// Admin struct for json-rpc, only served on the local admin listener
+type Admin struct{}
+
+// P2P return connected peers, topics mesh, DHT and peerstore state
+func (t *Admin) P2P(r *http.Request, args *AdminArgs, result *p2p.Status) error {
+	p2P := internal.P2PFromContext(r.Context())
+	if p2P == nil {
+		return errors.New("p2p not found")
+	}
+
+	*result = p2P.Status()
+	return nil
+}
+
+// AdminSnapshot for json-rpc request and response
+type AdminSnapshot struct {
+	Entries []soroban.DirectorySnapshotEntry
+	// Owners of entries added by signed requests
+	Owners []EntryOwner `json:",omitempty"`
+}
+
+// Snapshot return all directory entries and their owners
+func (t *Admin) Snapshot(r *http.Request, args *AdminArgs, result *AdminSnapshot) error {
+	snapshot, err := directorySnapshot(r.Context())
+	if err != nil {
+		return err
+	}
+
+	entries, err := snapshot.Snapshot()
+	if err != nil {
+		return err
+	}
+	*result = AdminSnapshot{
+		Entries: entries,
+		Owners:  policy.snapshotOwners(),
+	}
+	return nil
+}
+
+// Restore add snapshot entries and owners to directory
+func (t *Admin) Restore(r *http.Request, args *AdminSnapshot, result *api.Response) error {
+	snapshot, err := directorySnapshot(r.Context())
+	if err != nil {
+		return err
+	}
+
+	err = snapshot.Restore(args.Entries)
+	if err != nil {
+		return err
+	}
+	policy.restoreOwners(args.Owners)
+	*result = api.Response{
+		Status: "success",
+	}
+	return nil
+}
+
+func directorySnapshot(ctx context.Context) (soroban.DirectorySnapshot, error) {
+	directory := internal.DirectoryFromContext(ctx)
+	if directory == nil {
+		return nil, errors.New("directory not found")
+	}
+	snapshot, ok := directory.(soroban.DirectorySnapshot)
+	if !ok {
+		return nil, errors.New("directory does not support snapshot")
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+
+	"golang.org/x/crypto/nacl/sign"
+)
+
+func Test_AdminSnapshotRestore(t *testing.T) {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfidential(t, confidential.ConfidentialEntry{
+		Prefix:    "admin.*",
+		Algorithm: confidential.AlgorithmNacl,
+		PublicKey: hex.EncodeToString(publicKey[:]),
+		Add:       confidential.PermissionSigned,
+		Remove:    confidential.PermissionOwner,
+	})
+	source := internal.DefaultDirectory("")
+	add := signedEntry(publicKey, privateKey, api.MethodAdd, "admin.key", "entry", "01")
+	if err := addToDirectory(source, &add, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Add("public.key", "value", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	var admin Admin
+	r := httptest.NewRequest("POST", "/rpc", nil)
+	var snapshot AdminSnapshot
+	err = admin.Snapshot(r.WithContext(context.WithValue(r.Context(), internal.SorobanDirectoryKey, source)), &AdminArgs{}, &snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Entries) != 2 || len(snapshot.Owners) == 0 {
+		t.Fatalf("Snapshot() = %+v", snapshot)
+	}
+
+	// snapshot is sent as json by admin client
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored AdminSnapshot
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+
+	// restart: owners are lost
+	policy.removeOwner("admin.key", "entry")
+	target := internal.DefaultDirectory("")
+	var result api.Response
+	err = admin.Restore(r.WithContext(context.WithValue(r.Context(), internal.SorobanDirectoryKey, target)), &restored, &result)
+	if err != nil || result.Status != "success" {
+		t.Fatalf("Restore() = %v, %v", result, err)
+	}
+
+	for _, name := range []string{"admin.key", "public.key"} {
+		if entries, _ := target.List(name); len(entries) != 1 {
+			t.Errorf("List(%s) = %v", name, entries)
+		}
+	}
+	remove := signedEntry(publicKey, privateKey, api.MethodRemove, "admin.key", "entry", "02")
+	if err := removeFromDirectory(target, &remove, nil); err != nil {
+		t.Errorf("removeFromDirectory() restored owner = %v", err)
+	}
+}
@@ -2,20 +2,118 @@ package services
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"sync"
 	"time"
 
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
 	"code.samourai.io/wallet/samourai-soroban/internal"
+
+	torEd25519 "github.com/cretz/bine/torutil/ed25519"
 	log "github.com/sirupsen/logrus"
 )
 
-type AnnounceInfo struct {
-	Version string `json:"version"`
-	Url     string `json:"url"`
+// AnnounceOptions configure node announces
+type AnnounceOptions struct {
+	Key          string
+	Version      string
+	URLs         []string
+	Interval     time.Duration
+	Mode         string
+	Capabilities api.AnnounceCapabilities
+	// Signer is the node onion key, announces are not signed if nil
+	Signer crypto.Signer
+	// Load return the current node load
+	Load func() int
+}
+
+// Methods return json-rpc methods served by directory and node services
+func Methods() []string {
+	return []string{api.MethodList, api.MethodAdd, api.MethodRemove, api.MethodNodeInfo}
+}
+
+// signAnnounce sign announce with node onion key
+func signAnnounce(info *api.AnnounceInfo, signer crypto.Signer) error {
+	publicKey, err := signerPublicKey(signer)
+	if err != nil {
+		return err
+	}
+	info.Timestamp = time.Now().UnixNano()
+	info.PublicKey = hex.EncodeToString(publicKey)
+
+	message, err := info.Message()
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Sign(nil, message, crypto.Hash(0))
+	if err != nil {
+		return err
+	}
+	info.Signature = hex.EncodeToString(signature)
+	return nil
+}
+
+func signerPublicKey(signer crypto.Signer) ([]byte, error) {
+	switch key := signer.Public().(type) {
+	case ed25519.PublicKey:
+		return key, nil
+	case torEd25519.PublicKey:
+		return key, nil
+	default:
+		return nil, errors.New("unsupported announce key")
+	}
+}
+
+// announcePolicy validate entries of announce key before they are applied
+type announcePolicy struct {
+	mtx      sync.RWMutex
+	key      string
+	unsigned bool
+	keys     []string
 }
 
-func StartAnnounce(ctx context.Context, announceKey string, version string, nodeURLs ...string) {
+var announces = &announcePolicy{}
+
+// SetAnnouncePolicy configure announce key validation, unverified announces are rejected unless allowed.
+// Keys are allowed to sign announces of non-onion urls.
+func SetAnnouncePolicy(key string, unsigned bool, keys []string) {
+	announces.mtx.Lock()
+	defer announces.mtx.Unlock()
+
+	announces.key = key
+	announces.unsigned = unsigned
+	announces.keys = keys
+}
+
+// validate announce entry if name is the announce key
+func (p *announcePolicy) validate(directory soroban.Directory, args *api.DirectoryEntry) error {
+	p.mtx.RLock()
+	key, unsigned, keys := p.key, p.unsigned, p.keys
+	p.mtx.RUnlock()
+
+	if len(key) == 0 || args.Name != key {
+		return nil
+	}
+
+	var info api.AnnounceInfo
+	err := json.Unmarshal([]byte(args.Entry), &info)
+	if err != nil {
+		return api.ErrAnnounceMalformed
+	}
+	err = info.Verify(directory.TimeToLive(args.Mode), keys...)
+	if api.Unverified(err) && unsigned {
+		return nil
+	}
+	return err
+}
+
+func StartAnnounce(ctx context.Context, options AnnounceOptions) {
 	directory := internal.DirectoryFromContext(ctx)
 	if directory == nil {
 		log.Error("directory not found in context")
@@ -28,34 +126,45 @@ func StartAnnounce(ctx context.Context, announceKey string, version string, node
 		return
 	}
 
-	ticker := time.NewTicker(15 * time.Second)
+	interval := options.Interval
+	if interval <= 0 {
+		interval = soroban.DefaultOptions.Soroban.AnnounceInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	dir := new(Directory)
 
+	// announces are re-added unchanged to refresh their time to live,
+	// they are signed again with current load when half of time to live elapsed
+	refresh := directory.TimeToLive(options.Mode) / 2
+
+	// previous announces are removed when replaced
+	previous := make(map[string]announceEntry)
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info("Exiting Announce Loop")
 			return
 		case <-ticker.C:
-			for _, nodeURL := range nodeURLs {
+			for _, nodeURL := range options.URLs {
 				log.WithField("nodeURL", nodeURL).Info("Announce")
 
-				info := AnnounceInfo{
-					Version: version,
-					Url:     nodeURL,
-				}
-				data, err := json.Marshal(&info)
-				if err != nil {
-					log.WithError(err).Error("failed to marshal announce info")
-					break
+				current, ok := previous[nodeURL]
+				if !ok || time.Since(current.createdAt) >= refresh {
+					entry, err := newAnnounceEntry(options, nodeURL)
+					if err != nil {
+						log.WithError(err).Error("failed to create announce")
+						break
+					}
+					current = announceEntry{entry: entry, createdAt: time.Now()}
 				}
 
-				directoryEntry := DirectoryEntry{
-					Name:  announceKey,
-					Entry: string(data),
-					Mode:  "short",
+				directoryEntry := api.DirectoryEntry{
+					Name:  options.Key,
+					Entry: current.entry,
+					Mode:  options.Mode,
 				}
 
 				req, err := http.NewRequestWithContext(ctx, "POST", "", nil)
@@ -64,12 +173,51 @@ func StartAnnounce(ctx context.Context, announceKey string, version string, node
 					break
 				}
 
-				var resp Response
+				var resp api.Response
 				err = dir.Add(req, &directoryEntry, &resp)
-				if err != nil {
+				if err != nil || resp.Status != "success" {
 					log.WithError(err).Error("Failed to announce to directory")
+					continue
 				}
+
+				if entry, ok := previous[nodeURL]; ok && entry.entry != current.entry {
+					err = dir.Remove(req, &api.DirectoryEntry{Name: options.Key, Entry: entry.entry}, &resp)
+					if err != nil {
+						log.WithError(err).Error("Failed to remove previous announce")
+					}
+				}
+				previous[nodeURL] = current
 			}
 		}
 	}
 }
+
+// announceEntry is the announce directory entry of a node url
+type announceEntry struct {
+	entry     string
+	createdAt time.Time
+}
+
+// newAnnounceEntry return json announce of nodeURL, signed if options has a signer
+func newAnnounceEntry(options AnnounceOptions, nodeURL string) (string, error) {
+	capabilities := options.Capabilities
+	info := api.AnnounceInfo{
+		Version:      options.Version,
+		Url:          nodeURL,
+		Capabilities: &capabilities,
+	}
+	if options.Load != nil {
+		info.Load = options.Load()
+	}
+	if options.Signer != nil {
+		err := signAnnounce(&info, options.Signer)
+		if err != nil {
+			return "", err
+		}
+	}
+	data, err := json.Marshal(&info)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+
+	torEd25519 "github.com/cretz/bine/torutil/ed25519"
+)
+
+func Test_signAnnounce(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onion := "http://" + api.OnionServiceID(publicKey) + ".onion"
+
+	// onion keys are tor expanded keys
+	for _, signer := range []crypto.Signer{privateKey, torEd25519.FromCryptoPrivateKey(privateKey)} {
+		info := api.AnnounceInfo{
+			Version:      "1.0",
+			Url:          onion,
+			Capabilities: &api.AnnounceCapabilities{Methods: Methods(), P2P: true},
+			Load:         12,
+		}
+		err := signAnnounce(&info, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := info.Verify(time.Minute); err != nil {
+			t.Errorf("Verify() = %v", err)
+		}
+	}
+}
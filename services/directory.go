@@ -3,12 +3,11 @@ package services
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"math/rand"
 	"net/http"
-	"time"
 
 	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
 	"code.samourai.io/wallet/samourai-soroban/confidential"
 	"code.samourai.io/wallet/samourai-soroban/internal"
 	"code.samourai.io/wallet/samourai-soroban/ipc"
@@ -17,57 +16,38 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// DirectoryEntries for json-rpc request
-type DirectoryEntries struct {
-	Name      string
-	Limit     int
-	PublicKey string
-	Algorithm string
-	Signature string
-	Timestamp int64
-}
-
-// DirectoryEntriesResponse for json-rpc response
-type DirectoryEntriesResponse struct {
-	Name    string
-	Entries []string
-}
-
-// DirectoryEntry for json-rpc request
-type DirectoryEntry struct {
-	Name      string
-	Entry     string
-	Mode      string
-	PublicKey string
-	Algorithm string
-	Signature string
-	Timestamp int64
-}
-
 // Directory struct for json-rpc
 type Directory struct{}
 
-func (t *Directory) List(r *http.Request, args *DirectoryEntries, result *DirectoryEntriesResponse) error {
+func (t *Directory) List(r *http.Request, args *api.DirectoryEntries, result *api.DirectoryEntriesResponse) error {
 	directory := internal.DirectoryFromContext(r.Context())
 	if directory == nil {
 		log.Error("Directory not found")
 		return nil
 	}
 
-	info := confidential.GetConfidentialInfo(args.Name, args.PublicKey)
-	// check signature if key is confidential, list is not allowed for anonymous
-	if info.Confidential {
-		err := args.VerifySignature(info)
+	// signed requests are checked for replay before local or shard list
+	err := policy.authorizeList(args, confidential.DefaultReplayCache)
+	if err != nil {
+		log.WithError(err).Error("Failed to list directory")
+		return nil
+	}
+
+	var entries []string
+	// key is outside of node shards, query peers subscribed to key shard
+	if p2P := internal.P2PFromContext(r.Context()); p2P != nil && !p2P.HasShard(args.Name) {
+		entries, err = queryShard(r.Context(), p2P, args)
 		if err != nil {
-			log.WithError(err).Error("Failed to verifySignature")
-			return nil
+			log.WithError(err).Warning("Failed to query shard, fallback to local directory")
 		}
 	}
 
-	entries, err := directory.List(args.Name)
-	if err != nil {
-		log.WithError(err).Error("Failed to list directory")
-		return nil
+	if entries == nil {
+		entries, err = directory.List(args.Name)
+		if err != nil {
+			log.WithError(err).Error("Failed to list directory")
+			return nil
+		}
 	}
 
 	if args.Limit > 0 && args.Limit < len(entries) {
@@ -82,21 +62,48 @@ func (t *Directory) List(r *http.Request, args *DirectoryEntries, result *Direct
 	if entries == nil {
 		entries = make([]string, 0)
 	}
-	*result = DirectoryEntriesResponse{
+	*result = api.DirectoryEntriesResponse{
 		Name:    args.Name,
 		Entries: entries,
 	}
+	if signer := internal.SignerFromContext(r.Context()); signer != nil {
+		signListResponse(result, signer)
+	}
 	return nil
 }
 
-func addToDirectory(directory soroban.Directory, args *DirectoryEntry) error {
+func listDirectory(directory soroban.Directory, args *api.DirectoryEntries) ([]string, error) {
+	if args == nil {
+		return nil, errors.New("invalid args")
+	}
+
+	err := policy.authorizeList(args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return directory.List(args.Name)
+}
+
+// addToDirectory add entry if allowed by policy, for json-rpc and p2p requests.
+// Signed requests are checked against replay cache if not nil.
+func addToDirectory(directory soroban.Directory, args *api.DirectoryEntry, replay *confidential.ReplayCache) error {
 	if args == nil {
 		return errors.New("invalid args")
 	}
-	return directory.Add(args.Name, args.Entry, directory.TimeToLive(args.Mode))
+	info, signer, err := policy.authorizeAdd(args, replay)
+	if err != nil {
+		return err
+	}
+	err = announces.validate(directory, args)
+	if err != nil {
+		return err
+	}
+
+	return policy.add(directory, info, args, signer)
 }
 
-func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response) error {
+func (t *Directory) Add(r *http.Request, args *api.DirectoryEntry, result *api.Response) error {
 	ctx := r.Context()
 	directory := internal.DirectoryFromContext(ctx)
 	if directory == nil {
@@ -104,25 +111,12 @@ func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response)
 		return nil
 	}
 
-	info := confidential.GetConfidentialInfo(args.Name, args.PublicKey)
-	// check signature if key is readonly, add is not allowed for anonymous
-	if info.ReadOnly {
-		err := args.VerifySignature(info)
-		if err != nil {
-			log.WithError(err).Error("Failed to verifySignature")
-			*result = Response{
-				Status: "error",
-			}
-			return nil
-		}
-	}
-
 	log.Debugf("Add: %s %s", args.Name, args.Entry)
 
-	err := addToDirectory(directory, args)
+	err := addToDirectory(directory, args, confidential.DefaultReplayCache)
 	if err != nil {
 		log.WithError(err).Error("Failed to Add entry")
-		*result = Response{
+		*result = api.Response{
 			Status: "error",
 		}
 		return nil
@@ -133,7 +127,7 @@ func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response)
 		message, err := p2p.NewMessage("Directory.Add", &args)
 		if err != nil {
 			log.WithError(err).Error("failed to marshal p2P message.")
-			*result = Response{
+			*result = api.Response{
 				Status: "error",
 			}
 			return nil
@@ -142,7 +136,7 @@ func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response)
 		data, err := json.Marshal(message)
 		if err != nil {
 			log.WithError(err).Error("failed to marshal p2p message")
-			*result = Response{
+			*result = api.Response{
 				Status: "error",
 			}
 			return nil
@@ -153,7 +147,7 @@ func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response)
 		}, "down")
 		if err != nil {
 			log.WithError(err).Error("IPC requext failed")
-			*result = Response{
+			*result = api.Response{
 				Status: "error",
 			}
 			return nil
@@ -166,13 +160,13 @@ func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response)
 
 	if p2P := internal.P2PFromContext(ctx); p2P != nil {
 
-		err := p2P.PublishJson(ctx, "Directory.Add", args)
+		err := p2P.PublishJsonToShard(ctx, args.Name, "Directory.Add", args)
 		if err != nil {
 			// non fatal error
 			log.Printf("p2P - Failed to PublishJson. %s\n", err)
 		}
 
-		*result = Response{
+		*result = api.Response{
 			Status: "success",
 		}
 	} else {
@@ -183,14 +177,26 @@ func (t *Directory) Add(r *http.Request, args *DirectoryEntry, result *Response)
 	return nil
 }
 
-func removeFromDirectory(directory soroban.Directory, args *DirectoryEntry) error {
+// removeFromDirectory remove entry if allowed by policy, for json-rpc and p2p requests.
+// Signed requests are checked against replay cache if not nil.
+func removeFromDirectory(directory soroban.Directory, args *api.DirectoryEntry, replay *confidential.ReplayCache) error {
 	if args == nil {
 		return errors.New("invalid args")
 	}
-	return directory.Remove(args.Name, args.Entry)
+	err := policy.authorizeRemove(args, replay)
+	if err != nil {
+		return err
+	}
+
+	err = directory.Remove(args.Name, args.Entry)
+	if err != nil {
+		return err
+	}
+	policy.removeOwner(args.Name, args.Entry)
+	return nil
 }
 
-func (t *Directory) Remove(r *http.Request, args *DirectoryEntry, result *Response) error {
+func (t *Directory) Remove(r *http.Request, args *api.DirectoryEntry, result *api.Response) error {
 	ctx := r.Context()
 	directory := internal.DirectoryFromContext(ctx)
 	if directory == nil {
@@ -198,16 +204,6 @@ func (t *Directory) Remove(r *http.Request, args *DirectoryEntry, result *Respon
 		return nil
 	}
 
-	info := confidential.GetConfidentialInfo(args.Name, args.PublicKey)
-	// check signature if key is readonly, remove is not allowed for anonymous
-	if info.ReadOnly {
-		err := args.VerifySignature(info)
-		if err != nil {
-			log.WithError(err).Error("Failed to verifySignature")
-			return nil
-		}
-	}
-
 	p2P := internal.P2PFromContext(ctx)
 	if p2P == nil {
 		log.Println("p2P - P2P not found")
@@ -216,66 +212,23 @@ func (t *Directory) Remove(r *http.Request, args *DirectoryEntry, result *Respon
 
 	log.Debugf("Remove: %s %s", args.Name, args.Entry)
 
-	status := "success"
-	err := removeFromDirectory(directory, args)
+	err := removeFromDirectory(directory, args, confidential.DefaultReplayCache)
 	if err != nil {
-		status = "error"
 		log.WithError(err).Error("Failed to Remove directory")
+		*result = api.Response{
+			Status: "error",
+		}
+		return nil
 	}
 
-	err = p2P.PublishJson(ctx, "Directory.Remove", args)
+	err = p2P.PublishJsonToShard(ctx, args.Name, "Directory.Remove", args)
 	if err != nil {
 		// non fatal error
 		log.Printf("p2P - Failed to PublishJson. %s\n", err)
 	}
 
-	*result = Response{
-		Status: status,
+	*result = api.Response{
+		Status: "success",
 	}
 	return nil
 }
-
-func timeInRange(start, end, check time.Time) bool {
-	return check.After(start) && check.Before(end)
-}
-
-func (p *DirectoryEntries) VerifySignature(info confidential.ConfidentialEntry) error {
-	if len(info.Prefix) == 0 || len(info.Algorithm) == 0 || len(info.PublicKey) == 0 {
-		return nil
-	}
-
-	now := time.Now().UTC()
-	timestamp := time.Unix(0, p.Timestamp).UTC()
-	log.WithField("Timestamp", timestamp).Warning("VerifySignature")
-	delta := 24 * time.Hour
-
-	if p.PublicKey != info.PublicKey {
-		return errors.New("PublicKey not allowed")
-	}
-
-	if !timeInRange(now.Add(-delta), now.Add(delta), timestamp) {
-		return errors.New("timestamp not in time range")
-	}
-
-	message := fmt.Sprintf("%v.%v", p.Name, p.Timestamp)
-	return confidential.VerifySignature(info, p.PublicKey, message, p.Algorithm, p.Signature)
-}
-
-func (p *DirectoryEntry) VerifySignature(info confidential.ConfidentialEntry) error {
-	if len(info.Prefix) == 0 || len(info.Algorithm) == 0 || len(info.PublicKey) == 0 {
-		return nil
-	}
-
-	if p.PublicKey != info.PublicKey {
-		return errors.New("PublicKey not allowed")
-	}
-
-	now := time.Now().UTC()
-	timestamp := time.Unix(0, p.Timestamp).UTC()
-	delta := 24 * time.Hour
-	if !timeInRange(now.Add(-delta), now.Add(delta), timestamp) {
-		return errors.New("timestamp not in time range")
-	}
-	message := fmt.Sprintf("%s.%d.%s", p.Name, p.Timestamp, p.Entry)
-	return confidential.VerifySignature(info, p.PublicKey, message, p.Algorithm, p.Signature)
-}
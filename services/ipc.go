@@ -7,6 +7,8 @@ import (
 	"fmt"
 
 	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
 	"code.samourai.io/wallet/samourai-soroban/internal"
 	"code.samourai.io/wallet/samourai-soroban/ipc"
 	"code.samourai.io/wallet/samourai-soroban/p2p"
@@ -39,7 +41,23 @@ func ipcHandler(ctx context.Context, directory soroban.Directory, message ipc.Me
 
 		log.WithField("p2pMessage", fmt.Sprintf("%s: %s", p2pMessage.Context, string(p2pMessage.Payload))).Debug("Recieve message from IPC")
 
-		var args DirectoryEntry
+		if p2pMessage.Context == "Directory.List" {
+			response, err := handleListQuery(directory, p2pMessage.Payload)
+			if err != nil {
+				log.WithError(err).Error("failed to process query.")
+				return ipc.Message{
+					Type:    message.Type,
+					Message: "error",
+				}, nil
+			}
+			return ipc.Message{
+				Type:    message.Type,
+				Message: "success",
+				Payload: string(response),
+			}, nil
+		}
+
+		var args api.DirectoryEntry
 
 		err = p2pMessage.ParsePayload(&args)
 		if err != nil {
@@ -51,16 +69,14 @@ func ipcHandler(ctx context.Context, directory soroban.Directory, message ipc.Me
 			}, nil
 		}
 
-		switch p2pMessage.Context {
-		case "Directory.Add":
-			err = addToDirectory(directory, &args)
-
-		case "Directory.Remove":
-			err = removeFromDirectory(directory, &args)
-
-		default:
-			err = errors.New("unknown p2p message context")
-
+		err = applyP2PMessage(directory, p2pMessage.Context, &args)
+		if errors.Is(err, confidential.ErrReplayedRequest) {
+			// same message forwarded by several children
+			log.WithField("Context", p2pMessage.Context).Debug("Replayed p2p message ignored")
+			return ipc.Message{
+				Type:    message.Type,
+				Message: "success",
+			}, nil
 		}
 		if err != nil {
 			log.WithError(err).Error("failed to process message.")
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+)
+
+// Node struct for json-rpc
+type Node struct{}
+
+// Info return node signing public key
+func (t *Node) Info(r *http.Request, args *api.NodeInfoArgs, result *api.NodeInfoResponse) error {
+	signer := internal.SignerFromContext(r.Context())
+	if signer == nil {
+		*result = api.NodeInfoResponse{}
+		return nil
+	}
+
+	publicKey := signer.Public().(ed25519.PublicKey)
+	*result = api.NodeInfoResponse{
+		PublicKey: hex.EncodeToString(publicKey),
+		Algorithm: api.AlgorithmEd25519,
+		Onion:     api.OnionServiceID(publicKey),
+	}
+	return nil
+}
+
+// signListResponse sign response with node signing key
+func signListResponse(response *api.DirectoryEntriesResponse, signer ed25519.PrivateKey) {
+	response.Timestamp = time.Now().UnixNano()
+	response.PublicKey = hex.EncodeToString(signer.Public().(ed25519.PublicKey))
+	response.Signature = hex.EncodeToString(ed25519.Sign(signer, []byte(api.ListResponseMessage(response.Name, response.Timestamp, response.Entries))))
+}
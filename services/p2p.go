@@ -3,18 +3,22 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
 	"code.samourai.io/wallet/samourai-soroban/internal"
 	"code.samourai.io/wallet/samourai-soroban/ipc"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
 	log "github.com/sirupsen/logrus"
 )
 
 func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan struct{}) {
-	if len(options.P2P.Bootstrap) == 0 {
+	if len(options.P2P.Bootstrap) == 0 && !options.P2P.MDNS {
 		log.Error("Invalid bootstrap")
 		return
 	}
@@ -35,6 +39,32 @@ func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan
 		return
 	}
 
+	// answer list queries for keys in node shards
+	p2P.OnQuery = func(ctx context.Context, request []byte) ([]byte, error) {
+		if sorobanMode == "child" {
+			data, err := json.Marshal(p2p.Message{
+				Context: "Directory.List",
+				Payload: request,
+			})
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Request(ipc.Message{
+				Type:    ipc.MessageTypeSoroban,
+				Payload: string(data),
+			}, "up")
+			if err != nil {
+				return nil, err
+			}
+			if resp.Message != "success" {
+				return nil, errors.New("IPC query failed")
+			}
+			return []byte(resp.Payload), nil
+		}
+
+		return handleListQuery(internal.DirectoryFromContext(ctx), request)
+	}
+
 	p2pReady := make(chan struct{})
 	go func() {
 		err := p2P.Start(ctx, options.P2P, options.Gossip, p2pReady)
@@ -46,12 +76,38 @@ func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan
 
 	<-p2pReady
 
-	timeoutDelay := 15 * time.Minute // first timeout is longer at startup
+	heartbeat := options.Heartbeat
+	if heartbeat.Interval <= 0 {
+		heartbeat.Interval = soroban.DefaultOptions.Heartbeat.Interval
+	}
+	ticker := time.NewTicker(heartbeat.Interval)
+	defer ticker.Stop()
+
+	recovery := strings.Split(heartbeat.Recovery, ",")
+	recoveryAttempt := 0
+
+	timeoutDelay := heartbeat.StartupTimeout // first timeout is longer at startup
 	lastHeartbeatTimestamp := time.Now().UTC()
 	for {
 		select {
 		case message := <-p2P.OnMessage:
-			var args DirectoryEntry
+			if message.Context == p2p.HeartbeatContext {
+				var hb p2p.Heartbeat
+				err := message.ParsePayload(&hb)
+				if err != nil {
+					log.WithError(err).Error("Failed to ParsePayload")
+					continue
+				}
+				timeoutDelay = heartbeat.Timeout // reduce timeout delay after first heartbeat received
+				lastHeartbeatTimestamp = time.Now()
+				recoveryAttempt = 0
+				p2P.RecordNodeHeartbeat(message.Origin, hb)
+
+				log.WithField("Origin", message.Origin).Trace("p2p - heartbeat received")
+				continue
+			}
+
+			var args api.DirectoryEntry
 
 			err := message.ParsePayload(&args)
 			if err != nil {
@@ -59,9 +115,11 @@ func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan
 				continue
 			}
 
-			if args.Name == "p2p.heartbeat" {
-				timeoutDelay = 3 * time.Minute // reduce timeout delay after first heartbeat received
+			if args.Name == p2p.LegacyHeartbeatKey {
+				timeoutDelay = heartbeat.Timeout // reduce timeout delay after first heartbeat received
 				lastHeartbeatTimestamp = time.Now()
+				recoveryAttempt = 0
+				p2P.RecordHeartbeat()
 
 				log.Trace("p2p - heartbeat received")
 				continue
@@ -99,12 +157,10 @@ func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan
 					continue
 				}
 
-				switch message.Context {
-				case "Directory.Add":
-					err = addToDirectory(directory, &args)
-
-				case "Directory.Remove":
-					err = removeFromDirectory(directory, &args)
+				err = applyP2PMessage(directory, message.Context, &args)
+				if errors.Is(err, confidential.ErrReplayedRequest) {
+					log.WithField("Context", message.Context).Debug("Replayed p2p message ignored")
+					continue
 				}
 				if err != nil {
 					log.WithError(err).Error("failed to process message.")
@@ -121,23 +177,43 @@ func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan
 				continue
 			}
 
-		case <-time.After(30 * time.Second):
+		case <-ticker.C:
 			if time.Since(lastHeartbeatTimestamp) > timeoutDelay {
-				log.Warning("No message received from too long, exiting...")
-				soroban.Shutdown(ctx)
-				os.Exit(0)
+				if recoveryAttempt >= len(recovery) {
+					recoveryAttempt = len(recovery) - 1 // keep using the last action
+				}
+				action := strings.TrimSpace(recovery[recoveryAttempt])
+				recoveryAttempt++
+
+				log.WithField("Action", action).Warning("No heartbeat received from too long, recovering...")
+				err := p2P.Recover(ctx, action)
+				if err != nil {
+					log.WithError(err).WithField("Action", action).Error("Failed to recover p2p")
+				}
+				// give some time to the action before next attempt
+				lastHeartbeatTimestamp = time.Now()
+				continue
 			}
 
-			err := p2P.PublishJson(ctx, "Directory.Add", DirectoryEntry{
-				Name:  "p2p.heartbeat",
-				Entry: fmt.Sprintf("%d", time.Now().Unix()),
-				Mode:  "short",
-			})
+			err := p2P.PublishHeartbeat(ctx, options.Version)
 			if err != nil {
 				// non fatal error
-				log.Warningf("p2p - Failed to PublishJson. %s\n", err)
+				log.Warningf("p2p - Failed to PublishHeartbeat. %s\n", err)
 				continue
 			}
+			// older nodes only understand directory heartbeats
+			if options.P2P.MessageFormat != p2p.MessageFormatEnvelope {
+				err = p2P.PublishJson(ctx, "Directory.Add", api.DirectoryEntry{
+					Name:  p2p.LegacyHeartbeatKey,
+					Entry: fmt.Sprintf("%d", time.Now().Unix()),
+					Mode:  "short",
+				})
+				if err != nil {
+					// non fatal error
+					log.Warningf("p2p - Failed to PublishJson. %s\n", err)
+					continue
+				}
+			}
 			log.Trace("p2p - heartbeat sent")
 
 		case <-ctx.Done():
@@ -145,3 +221,18 @@ func StartP2PDirectory(ctx context.Context, options soroban.Options, ready chan
 		}
 	}
 }
+
+// applyP2PMessage apply directory message received from p2p or forwarded by IPC children.
+// Signed requests share the json-rpc replay cache, a request is applied once whatever the path.
+func applyP2PMessage(directory soroban.Directory, context string, args *api.DirectoryEntry) error {
+	switch context {
+	case "Directory.Add":
+		return addToDirectory(directory, args, confidential.DefaultReplayCache)
+
+	case "Directory.Remove":
+		return removeFromDirectory(directory, args, confidential.DefaultReplayCache)
+
+	default:
+		return errors.New("unknown p2p message context")
+	}
+}
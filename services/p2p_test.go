@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+	"code.samourai.io/wallet/samourai-soroban/ipc"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+
+	"golang.org/x/crypto/nacl/sign"
+)
+
+func Test_P2PReplayedRemove(t *testing.T) {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfidential(t, confidential.ConfidentialEntry{
+		Prefix:       "replay.*",
+		Algorithm:    confidential.AlgorithmNacl,
+		PublicKey:    hex.EncodeToString(publicKey[:]),
+		Confidential: true,
+	})
+
+	forward := func(context string, args api.DirectoryEntry) ipc.Message {
+		message, err := p2p.NewMessage(context, &args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ipc.Message{Type: ipc.MessageTypeSoroban, Payload: string(data)}
+	}
+
+	directory := internal.DefaultDirectory("")
+	add := signedEntry(publicKey, privateKey, api.MethodAdd, "replay.key", "entry", "01")
+	remove := signedEntry(publicKey, privateKey, api.MethodRemove, "replay.key", "entry", "02")
+
+	// direct p2p path
+	if err := applyP2PMessage(directory, "Directory.Add", &add); err != nil {
+		t.Fatalf("applyP2PMessage() add = %v", err)
+	}
+	if err := applyP2PMessage(directory, "Directory.Remove", &remove); err != nil {
+		t.Fatalf("applyP2PMessage() remove = %v", err)
+	}
+	// entry is added again, a replayed remove must not delete it
+	readd := signedEntry(publicKey, privateKey, api.MethodAdd, "replay.key", "entry", "03")
+	if err := applyP2PMessage(directory, "Directory.Add", &readd); err != nil {
+		t.Fatalf("applyP2PMessage() add = %v", err)
+	}
+	if err := applyP2PMessage(directory, "Directory.Remove", &remove); !errors.Is(err, confidential.ErrReplayedRequest) {
+		t.Errorf("applyP2PMessage() replayed remove = %v", err)
+	}
+
+	// path of p2p messages forwarded by IPC children
+	result, err := ipcHandler(context.Background(), directory, forward("Directory.Remove", remove))
+	if err != nil || result.Message != "success" {
+		t.Errorf("ipcHandler() replayed remove = %v, %v", result, err)
+	}
+
+	entries, err := directory.List("replay.key")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("List() = %v, %v", entries, err)
+	}
+}
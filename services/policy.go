@@ -0,0 +1,260 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+)
+
+// interval between expired owners cleanup
+const ownersPruneInterval = time.Minute
+
+// directoryPolicy evaluate confidential rules, entry owners and quotas for directory operations.
+// Used by json-rpc requests and writes applied from p2p.
+// Owners are kept in memory: other nodes record them from signed adds received from p2p,
+// they are saved with admin snapshots, entries without known owner can't be removed by owner.
+type directoryPolicy struct {
+	mtx       sync.Mutex
+	owners    map[string]entryOwner
+	lastPrune time.Time
+}
+
+type entryOwner struct {
+	PublicKey string
+	ExpireOn  time.Time
+}
+
+// EntryOwner is the public key which added an entry, Key is the hash of entry name and value
+type EntryOwner struct {
+	Key       string
+	PublicKey string
+	ExpireOn  time.Time
+}
+
+var policy = &directoryPolicy{
+	owners: make(map[string]entryOwner),
+}
+
+// listRequest return signed request of list args
+func listRequest(p *api.DirectoryEntries) confidential.SignedRequest {
+	return confidential.SignedRequest{
+		PublicKey: p.PublicKey,
+		Algorithm: p.Algorithm,
+		Signature: p.Signature,
+		Timestamp: p.Timestamp,
+		Message:   confidential.RequestMessage(p.Version, api.MethodList, p.Name, p.Timestamp, p.Nonce),
+		Version:   p.Version,
+	}
+}
+
+// entryRequest return signed request of method args
+func entryRequest(p *api.DirectoryEntry, method string) confidential.SignedRequest {
+	return confidential.SignedRequest{
+		PublicKey: p.PublicKey,
+		Algorithm: p.Algorithm,
+		Signature: p.Signature,
+		Timestamp: p.Timestamp,
+		Message:   confidential.RequestMessage(p.Version, method, p.Name, p.Timestamp, p.Nonce, p.Entry),
+		Version:   p.Version,
+	}
+}
+
+// authorize check operation permission, verified requests are checked against replay cache if not nil.
+// Rule error is set when request key is not authorized by any rule, operations with signed permission are denied.
+func (p *directoryPolicy) authorize(info confidential.ConfidentialEntry, ruleErr error, operation string, request confidential.SignedRequest, replay *confidential.ReplayCache) (string, error) {
+	if ruleErr != nil && info.Permission(operation) == confidential.PermissionSigned {
+		return "", ruleErr
+	}
+	signer, err := info.Authorize(operation, request)
+	if err != nil {
+		return "", err
+	}
+	if len(signer) > 0 {
+		err = replay.Check(request)
+		if err != nil {
+			return "", err
+		}
+	}
+	return signer, nil
+}
+
+// authorizeList check list permission
+func (p *directoryPolicy) authorizeList(args *api.DirectoryEntries, replay *confidential.ReplayCache) error {
+	info, ruleErr := confidential.GetConfidentialInfo(args.Name, args.PublicKey)
+	_, err := p.authorize(info, ruleErr, confidential.OperationList, listRequest(args), replay)
+	return err
+}
+
+// authorizeAdd check add permission, return matching rule and signer
+func (p *directoryPolicy) authorizeAdd(args *api.DirectoryEntry, replay *confidential.ReplayCache) (confidential.ConfidentialEntry, string, error) {
+	info, ruleErr := confidential.GetConfidentialInfo(args.Name, args.PublicKey)
+	signer, err := p.authorize(info, ruleErr, confidential.OperationAdd, entryRequest(args, api.MethodAdd), replay)
+	if err != nil {
+		return confidential.ConfidentialEntry{}, "", err
+	}
+	return info, signer, nil
+}
+
+// add entry with rule time to live and record signer as owner.
+// Quota is checked and entry added under policy lock, concurrent adds can't exceed rule max entries.
+func (p *directoryPolicy) add(directory soroban.Directory, info confidential.ConfidentialEntry, args *api.DirectoryEntry, signer string) error {
+	ttl := info.TimeToLive(directory.TimeToLive(args.Mode))
+	if info.MaxEntries == 0 {
+		err := directory.Add(args.Name, args.Entry, ttl)
+		if err != nil {
+			return err
+		}
+		p.setOwner(args.Name, args.Entry, signer, ttl)
+		return nil
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	entries, err := directory.List(args.Name)
+	if err != nil {
+		return err
+	}
+	if len(entries) >= info.MaxEntries && !containsEntry(entries, args.Entry) {
+		return confidential.ErrQuotaExceeded
+	}
+	err = directory.Add(args.Name, args.Entry, ttl)
+	if err != nil {
+		return err
+	}
+	p.setOwnerLocked(args.Name, args.Entry, signer, ttl)
+	return nil
+}
+
+// authorizeRemove check remove permission, owner scoped entries can only be removed by the key which added them
+func (p *directoryPolicy) authorizeRemove(args *api.DirectoryEntry, replay *confidential.ReplayCache) error {
+	info, ruleErr := confidential.GetConfidentialInfo(args.Name, args.PublicKey)
+	signer, err := p.authorize(info, ruleErr, confidential.OperationRemove, entryRequest(args, api.MethodRemove), replay)
+	if err != nil {
+		return err
+	}
+
+	if info.Permission(confidential.OperationRemove) == confidential.PermissionOwner {
+		owner := p.owner(args.Name, args.Entry)
+		if len(owner) == 0 || owner != signer {
+			return confidential.ErrNotOwner
+		}
+	}
+	return nil
+}
+
+// ownerKey return hash of entry, names are not kept in clear
+func ownerKey(name, entry string) string {
+	hash := sha256.Sum256([]byte(name + "\x00" + entry))
+	return hex.EncodeToString(hash[:])
+}
+
+func (p *directoryPolicy) owner(name, entry string) string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	owner, ok := p.owners[ownerKey(name, entry)]
+	if !ok || owner.ExpireOn.Before(time.Now()) {
+		return ""
+	}
+	return owner.PublicKey
+}
+
+// setOwner record entry owner, an existing owner is kept
+func (p *directoryPolicy) setOwner(name, entry, publicKey string, ttl time.Duration) {
+	if len(publicKey) == 0 {
+		return
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.setOwnerLocked(name, entry, publicKey, ttl)
+}
+
+// setOwnerLocked record entry owner, policy lock must be held
+func (p *directoryPolicy) setOwnerLocked(name, entry, publicKey string, ttl time.Duration) {
+	if len(publicKey) == 0 {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(p.lastPrune) > ownersPruneInterval {
+		for key, owner := range p.owners {
+			if owner.ExpireOn.Before(now) {
+				delete(p.owners, key)
+			}
+		}
+		p.lastPrune = now
+	}
+
+	key := ownerKey(name, entry)
+	if owner, ok := p.owners[key]; ok && owner.ExpireOn.After(now) && owner.PublicKey != publicKey {
+		return
+	}
+	p.owners[key] = entryOwner{
+		PublicKey: publicKey,
+		ExpireOn:  now.Add(ttl),
+	}
+}
+
+func (p *directoryPolicy) removeOwner(name, entry string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	delete(p.owners, ownerKey(name, entry))
+}
+
+// snapshotOwners return owners of non-expired entries
+func (p *directoryPolicy) snapshotOwners() []EntryOwner {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	now := time.Now()
+	var result []EntryOwner
+	for key, owner := range p.owners {
+		if owner.ExpireOn.Before(now) {
+			continue
+		}
+		result = append(result, EntryOwner{
+			Key:       key,
+			PublicKey: owner.PublicKey,
+			ExpireOn:  owner.ExpireOn,
+		})
+	}
+	return result
+}
+
+// restoreOwners add owners of snapshot, existing owners are kept
+func (p *directoryPolicy) restoreOwners(owners []EntryOwner) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	now := time.Now()
+	for _, owner := range owners {
+		if owner.ExpireOn.Before(now) || len(owner.PublicKey) == 0 {
+			continue
+		}
+		if current, ok := p.owners[owner.Key]; ok && current.ExpireOn.After(now) {
+			continue
+		}
+		p.owners[owner.Key] = entryOwner{
+			PublicKey: owner.PublicKey,
+			ExpireOn:  owner.ExpireOn,
+		}
+	}
+}
+
+func containsEntry(entries []string, entry string) bool {
+	for _, e := range entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+
+	"golang.org/x/crypto/nacl/sign"
+)
+
+// signedEntry return directory entry signed with nacl key for method
+func signedEntry(publicKey *[32]byte, privateKey *[64]byte, method, name, entry, nonce string) api.DirectoryEntry {
+	args := api.DirectoryEntry{
+		Name:      name,
+		Entry:     entry,
+		Mode:      "short",
+		PublicKey: hex.EncodeToString(publicKey[:]),
+		Algorithm: confidential.AlgorithmNacl,
+		Timestamp: time.Now().UnixNano(),
+		Version:   confidential.SignatureV2,
+		Nonce:     nonce,
+	}
+	message := confidential.RequestMessage(args.Version, method, args.Name, args.Timestamp, args.Nonce, args.Entry)
+	args.Signature = hex.EncodeToString(sign.Sign(nil, []byte(message), privateKey)[:sign.Overhead])
+	return args
+}
+
+// setConfidential replace confidential rules during test
+func setConfidential(t *testing.T, entries ...confidential.ConfidentialEntry) {
+	previous := confidential.DefaultSorobanConfig
+	t.Cleanup(func() { confidential.DefaultSorobanConfig = previous })
+	confidential.DefaultSorobanConfig = confidential.SorobanConfig{Confidential: entries}
+}
+
+func Test_PolicyQuotaConcurrent(t *testing.T) {
+	setConfidential(t, confidential.ConfidentialEntry{
+		Prefix:     "quota.*",
+		Add:        confidential.PermissionAnyone,
+		MaxEntries: 5,
+	})
+
+	directory := internal.DefaultDirectory("")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := addToDirectory(directory, &api.DirectoryEntry{Name: "quota.key", Entry: fmt.Sprintf("entry%d", i), Mode: "short"}, nil)
+			if err != nil && !errors.Is(err, confidential.ErrQuotaExceeded) {
+				t.Errorf("addToDirectory() = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries, err := directory.List("quota.key")
+	if err != nil || len(entries) != 5 {
+		t.Errorf("List() = %d entries, %v", len(entries), err)
+	}
+}
+
+func Test_PolicyOwnersSnapshot(t *testing.T) {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfidential(t, confidential.ConfidentialEntry{
+		Prefix:    "owned.*",
+		Algorithm: confidential.AlgorithmNacl,
+		PublicKey: hex.EncodeToString(publicKey[:]),
+		Add:       confidential.PermissionSigned,
+		Remove:    confidential.PermissionOwner,
+	})
+
+	directory := internal.DefaultDirectory("")
+	add := signedEntry(publicKey, privateKey, api.MethodAdd, "owned.key", "entry", "01")
+	err = addToDirectory(directory, &add, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners := policy.snapshotOwners()
+
+	// owners are lost on restart and restored from snapshot
+	policy.removeOwner("owned.key", "entry")
+	remove := signedEntry(publicKey, privateKey, api.MethodRemove, "owned.key", "entry", "02")
+	if err := removeFromDirectory(directory, &remove, nil); !errors.Is(err, confidential.ErrNotOwner) {
+		t.Errorf("removeFromDirectory() unknown owner = %v", err)
+	}
+	policy.restoreOwners(owners)
+	if err := removeFromDirectory(directory, &remove, nil); err != nil {
+		t.Errorf("removeFromDirectory() = %v", err)
+	}
+}
+
+func Test_PolicyUnknownKey(t *testing.T) {
+	publicKey, privateKey, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, err := sign.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfidential(t,
+		confidential.ConfidentialEntry{
+			Prefix:    "signed.*",
+			Algorithm: confidential.AlgorithmNacl,
+			PublicKey: hex.EncodeToString(otherKey[:]),
+			Add:       confidential.PermissionSigned,
+		},
+		confidential.ConfidentialEntry{
+			Prefix: "mailbox.*",
+			Add:    confidential.PermissionAnyone,
+			Remove: confidential.PermissionOwner,
+		},
+	)
+	directory := internal.DefaultDirectory("")
+
+	// key not authorized by any rule
+	add := signedEntry(publicKey, privateKey, api.MethodAdd, "signed.key", "entry", "01")
+	if err := addToDirectory(directory, &add, nil); !errors.Is(err, confidential.ErrPublicKeyNotAllowed) {
+		t.Errorf("addToDirectory() signed = %v", err)
+	}
+
+	// owner permission is not bound to rule keys
+	add = signedEntry(publicKey, privateKey, api.MethodAdd, "mailbox.key", "entry", "02")
+	if err := addToDirectory(directory, &add, nil); err != nil {
+		t.Errorf("addToDirectory() mailbox = %v", err)
+	}
+	remove := signedEntry(publicKey, privateKey, api.MethodRemove, "mailbox.key", "entry", "03")
+	if err := removeFromDirectory(directory, &remove, nil); err != nil {
+		t.Errorf("removeFromDirectory() mailbox = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/confidential"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+	"code.samourai.io/wallet/samourai-soroban/internal/common"
+	"code.samourai.io/wallet/samourai-soroban/ipc"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ApplyOptions apply runtime options: log level, replay protection, announces, ttl modes and gossip parameters
+func ApplyOptions(ctx context.Context, options soroban.Options) {
+	if level, err := log.ParseLevel(options.LogLevel); err == nil {
+		log.SetLevel(level)
+	}
+	confidential.SetReplayProtection(options.Soroban.SignatureWindow, options.Soroban.ReplayCacheSize)
+	SetAnnouncePolicy(options.Soroban.Announce, options.Soroban.AnnounceUnsigned, options.Soroban.AnnounceKeyList())
+	common.SetTimeToLive(options.TTL.Modes())
+
+	if p2P := internal.P2PFromContext(ctx); p2P != nil {
+		err := p2P.ReloadGossip(ctx, options.Gossip)
+		if err != nil {
+			log.WithError(err).Error("Failed to reload gossip options")
+		}
+	}
+}
+
+// BroadcastReload send reloadable options to IPC child processes
+func BroadcastReload(ctx context.Context, options soroban.Options) error {
+	client := internal.IPCFromContext(ctx)
+	if client == nil || client.Mode() == "child" {
+		return nil
+	}
+
+	data, err := json.Marshal(options.Reloadable())
+	if err != nil {
+		return err
+	}
+	return client.Broadcast(ipc.Message{
+		Type:    ipc.MessageTypeReload,
+		Payload: string(data),
+	})
+}
@@ -20,6 +20,7 @@ type NamedService struct {
 func RegisterAll(ctx context.Context, server soroban.Soroban) error {
 	services := []NamedService{
 		{"directory", new(Directory)},
+		{"node", new(Node)},
 	}
 
 	for _, ns := range services {
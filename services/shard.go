@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	soroban "code.samourai.io/wallet/samourai-soroban"
+	"code.samourai.io/wallet/samourai-soroban/api"
+	"code.samourai.io/wallet/samourai-soroban/internal"
+	"code.samourai.io/wallet/samourai-soroban/ipc"
+	"code.samourai.io/wallet/samourai-soroban/p2p"
+)
+
+// queryShard list entries from peers subscribed to key shard.
+// Request is forwarded to a child process when p2p is running in IPC mode.
+func queryShard(ctx context.Context, p2P *p2p.P2P, args *api.DirectoryEntries) ([]string, error) {
+	request, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []byte
+	if p2P.Valid() {
+		response, err = p2P.Query(ctx, args.Name, request)
+		if err != nil {
+			return nil, err
+		}
+	} else if client := internal.IPCFromContext(ctx); client != nil {
+		data, err := json.Marshal(p2p.Message{
+			Context: "Directory.List",
+			Payload: request,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Request(ipc.Message{
+			Type:    ipc.MessageTypeIPC,
+			Payload: string(data),
+		}, "down")
+		if err != nil {
+			return nil, err
+		}
+		if resp.Message != "success" {
+			return nil, errors.New("IPC query failed")
+		}
+		response = []byte(resp.Payload)
+	} else {
+		return nil, p2p.ErrNoShardPeer
+	}
+
+	var result api.DirectoryEntriesResponse
+	err = json.Unmarshal(response, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Entries == nil {
+		result.Entries = make([]string, 0)
+	}
+	return result.Entries, nil
+}
+
+// handleListQuery list local directory for a query received from p2p or IPC
+func handleListQuery(directory soroban.Directory, request []byte) ([]byte, error) {
+	if directory == nil {
+		return nil, errors.New("directory not found")
+	}
+
+	var args api.DirectoryEntries
+	err := json.Unmarshal(request, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := listDirectory(directory, &args)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = make([]string, 0)
+	}
+
+	return json.Marshal(api.DirectoryEntriesResponse{
+		Name:    args.Name,
+		Entries: entries,
+	})
+}
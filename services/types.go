@@ -1,6 +1,2 @@
 // services package contains json-rpc services
 package services
-
-type Response struct {
-	Status string
-}
@@ -54,3 +54,25 @@ type Directory interface {
 	// Remove value from key.
 	Remove(key, value string) error
 }
+
+// DirectorySnapshotValue is a directory value with its expiration
+type DirectorySnapshotValue struct {
+	Value    string
+	ExpireOn time.Time
+}
+
+// DirectorySnapshotEntry is a directory key with its values, key is hashed with the directory domain
+type DirectorySnapshotEntry struct {
+	Key    string
+	TTL    time.Duration
+	Values []DirectorySnapshotValue
+}
+
+// DirectorySnapshot is implemented by directories supporting snapshot and restore
+type DirectorySnapshot interface {
+	// Snapshot return all non-expired keys and values.
+	Snapshot() ([]DirectorySnapshotEntry, error)
+
+	// Restore add entries to directory, expired values are ignored.
+	Restore(entries []DirectorySnapshotEntry) error
+}
@@ -0,0 +1,143 @@
+package soroban
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cretz/bine/control"
+	"github.com/cretz/bine/tor"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TorControlPasswordEnv can be used instead of Tor.ControlPassword
+const TorControlPasswordEnv = "SOROBAN_TOR_CONTROL_PASSWORD"
+
+// External return true if an existing tor daemon is used
+func (p TorInfo) External() bool {
+	return len(p.ControlAddress) > 0
+}
+
+// StartTor start an embedded tor with conf, or connect to the external tor daemon.
+// Embedded tor use a persistent data directory named after the instance when Tor.DataDir is set.
+// External tor connection is shared by all callers and is not stopped on close.
+func StartTor(ctx context.Context, options TorInfo, instance string, conf *tor.StartConf) (*tor.Tor, error) {
+	if !options.External() {
+		persistent := len(options.DataDir) > 0
+		if persistent {
+			dataDir := filepath.Join(options.DataDir, instance)
+			err := lockDataDir(dataDir)
+			if err != nil {
+				return nil, err
+			}
+			conf.DataDir = dataDir
+			conf.TempDataDirBase = ""
+			conf.RetainTempDataDir = true
+		}
+
+		t, err := tor.Start(ctx, conf)
+		if err != nil {
+			return nil, err
+		}
+		t.DeleteDataDirOnClose = !persistent
+		AddTorClient(ctx, t)
+		return t, nil
+	}
+
+	torClients := torClientsFromContext(ctx)
+	torClients.Lock()
+	defer torClients.Unlock()
+
+	if torClients.external != nil {
+		return torClients.external, nil
+	}
+
+	t, err := connectTor(options)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("ControlAddress", options.ControlAddress).Info("Connected to external tor")
+
+	torClients.external = t
+	torClients.clients = append(torClients.clients, t)
+	return t, nil
+}
+
+// connectTor connect and authenticate to tor control port with cookie or password
+func connectTor(options TorInfo) (*tor.Tor, error) {
+	textConn, err := textproto.Dial("tcp", options.ControlAddress)
+	if err != nil {
+		return nil, err
+	}
+	controlConn := control.NewConn(textConn)
+	controlConn.DebugWriter = io.Discard
+
+	password := options.ControlPassword
+	if len(password) == 0 {
+		password = os.Getenv(TorControlPasswordEnv)
+	}
+	err = controlConn.Authenticate(password)
+	if err != nil {
+		controlConn.Close()
+		return nil, err
+	}
+
+	return &tor.Tor{
+		Control:            controlConn,
+		DebugWriter:        io.Discard,
+		StopProcessOnClose: false,
+	}, nil
+}
+
+// TorDialer return a dialer using the configured socks port if any
+func TorDialer(ctx context.Context, options TorInfo, t *tor.Tor) (*tor.Dialer, error) {
+	if t == nil {
+		return nil, errors.New("tor not initialized")
+	}
+
+	var conf tor.DialConf
+	if options.External() {
+		conf.ProxyAddress = options.SocksAddress
+	}
+	return t.Dialer(ctx, &conf)
+}
+
+var (
+	dataDirLocksMtx sync.Mutex
+	dataDirLocks    = make(map[string]*os.File)
+)
+
+// lockDataDir create tor data directory and lock it for the process lifetime.
+// Locking fail if the directory is used by another process.
+func lockDataDir(dataDir string) error {
+	dataDirLocksMtx.Lock()
+	defer dataDirLocksMtx.Unlock()
+
+	if _, ok := dataDirLocks[dataDir]; ok {
+		// already locked by this process, tor instance is restarting
+		return nil
+	}
+
+	err := os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dataDir, "soroban.lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("tor data directory %s is used by another process: %w", dataDir, err)
+	}
+
+	dataDirLocks[dataDir] = file
+	return nil
+}